@@ -0,0 +1,34 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrMisalignedOperand = errors.New("misaligned operand")
+
+// VerifyOperandAlignment checks that every operand with an Alignment in table starts at an offset
+// that is a multiple of it. Offsets are relative to the start of opcodes, which is usually the start
+// of one function.
+func VerifyOperandAlignment(opcodes []byte, table OpcodeTable) error {
+	return forEachInstruction(opcodes, table, func(decoded decodedInstruction) error {
+		operandOffset := decoded.offset + 1
+
+		for index, operand := range decoded.instruction.Operands {
+			if operand.Alignment > 0 && operandOffset%operand.Alignment != 0 {
+				return fmt.Errorf("%w: operand %d of '%v' at offset %04X starts at %04X, not aligned to %d",
+					ErrMisalignedOperand, index, decoded.instruction.Mnemonic, decoded.offset, operandOffset,
+					operand.Alignment)
+			}
+
+			operandOffset += operand.OctetCount
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,35 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVerifyOperandAlignment(t *testing.T) {
+	opcodes := []byte{
+		opcodeReturn, opcodeReturn, opcodeReturn,
+		opcodePushInt, 0x00, 0x00, 0x00, 0x2a,
+		opcodeReturn,
+		opcodeLoadConstant, 0x00, 0x01,
+	}
+
+	if err := VerifyOperandAlignment(opcodes, testOpcodeTable); err != nil {
+		t.Fatalf("verify operand alignment: %v", err)
+	}
+}
+
+func TestVerifyOperandAlignmentMisaligned(t *testing.T) {
+	for _, opcodes := range [][]byte{
+		{opcodeLoadConstant, 0x00, 0x01},
+		{opcodeReturn, opcodeReturn, opcodePushInt, 0x00, 0x00, 0x00, 0x2a},
+	} {
+		if err := VerifyOperandAlignment(opcodes, testOpcodeTable); !errors.Is(err, ErrMisalignedOperand) {
+			t.Errorf("expected ErrMisalignedOperand for % x, got %v", opcodes, err)
+		}
+	}
+}
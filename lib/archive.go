@@ -0,0 +1,177 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+const (
+	IconArchive      raff.FourOctets = 0xF09F9783 // 🗃
+	IconArchiveEntry raff.FourOctets = IconPack
+)
+
+var (
+	archiveHeaderName = raff.MakeFourOctets('s', 'a', 'r', '0')
+	archiveEntryName  = raff.MakeFourOctets('s', 'a', 'e', '0')
+)
+
+var ErrNotAnArchive = errors.New("not a swamp pack archive")
+
+type archiveEntry struct {
+	name string
+	data []byte
+}
+
+// Archive is a RAFF container holding complete packs keyed by name.
+type Archive struct {
+	entries []archiveEntry
+}
+
+func NewArchive() *Archive {
+	return &Archive{}
+}
+
+// AddPack adds a pack to the archive, replacing any previously added pack with the same name.
+func (a *Archive) AddPack(name string, data []byte) {
+	for index, entry := range a.entries {
+		if entry.name == name {
+			a.entries[index].data = data
+			return
+		}
+	}
+
+	a.entries = append(a.entries, archiveEntry{name: name, data: data})
+}
+
+// Names returns the pack names in the order they were added.
+func (a *Archive) Names() []string {
+	names := make([]string, len(a.entries))
+	for index, entry := range a.entries {
+		names[index] = entry.name
+	}
+
+	return names
+}
+
+// Pack returns the pack stored under name.
+func (a *Archive) Pack(name string) ([]byte, bool) {
+	for _, entry := range a.entries {
+		if entry.name == name {
+			return entry.data, true
+		}
+	}
+
+	return nil, false
+}
+
+func writeArchiveEntry(writer io.Writer, entry archiveEntry) error {
+	if len(entry.name) > math.MaxUint16 {
+		return fmt.Errorf("archive pack name is too long (%d octets)", len(entry.name))
+	}
+
+	var payload bytes.Buffer
+
+	binary.Write(&payload, binary.BigEndian, uint16(len(entry.name)))
+	payload.WriteString(entry.name)
+	payload.Write(entry.data)
+
+	return writeChunkHeader(writer, IconArchiveEntry, archiveEntryName, payload.Bytes())
+}
+
+func (a *Archive) WriteArchive(writer io.Writer) error {
+	if err := raff.WriteHeader(writer); err != nil {
+		return fmt.Errorf("archive write header %w", err)
+	}
+
+	if err := writeChunkHeader(writer, IconArchive, archiveHeaderName, nil); err != nil {
+		return err
+	}
+
+	for _, entry := range a.entries {
+		if err := writeArchiveEntry(writer, entry); err != nil {
+			return fmt.Errorf("archive write pack '%v' %w", entry.name, err)
+		}
+	}
+
+	return nil
+}
+
+func readArchiveEntry(payload []byte) (archiveEntry, error) {
+	if len(payload) < 2 {
+		return archiveEntry{}, fmt.Errorf("archive entry is truncated")
+	}
+
+	nameOctetCount := int(binary.BigEndian.Uint16(payload))
+	if len(payload) < 2+nameOctetCount {
+		return archiveEntry{}, fmt.Errorf("archive entry name is truncated")
+	}
+
+	return archiveEntry{
+		name: string(payload[2 : 2+nameOctetCount]),
+		data: payload[2+nameOctetCount:],
+	}, nil
+}
+
+// OpenArchive reads an archive written by WriteArchive. DefaultLimits apply to each entry on its
+// own, so every pack must fit within them, while the number of packs in the archive is not limited.
+func OpenArchive(readerAt io.ReaderAt) (*Archive, error) {
+	reader := io.NewSectionReader(readerAt, 0, math.MaxInt64)
+
+	if err := readFileHeader(reader); err != nil {
+		if errors.Is(err, ErrNotASwampPack) {
+			return nil, ErrNotAnArchive
+		}
+
+		return nil, err
+	}
+
+	header, _, headerErr := newLimitedChunkReader(reader, Limits{}).readChunk()
+	if headerErr != nil {
+		return nil, fmt.Errorf("archive read header %w", headerErr)
+	}
+
+	if header.Name != archiveHeaderName || header.Icon != IconArchive {
+		return nil, ErrNotAnArchive
+	}
+
+	archive := NewArchive()
+
+	for {
+		entryHeader, payload, readErr := newLimitedChunkReader(reader, Limits{}).readChunk()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+
+		if readErr != nil {
+			return nil, fmt.Errorf("archive read pack %w", readErr)
+		}
+
+		if entryHeader.Name != archiveEntryName {
+			continue
+		}
+
+		if err := checkIcon(entryHeader, IconArchiveEntry); err != nil {
+			return nil, err
+		}
+
+		entry, entryErr := readArchiveEntry(payload)
+		if entryErr != nil {
+			return nil, entryErr
+		}
+
+		archive.AddPack(entry.name, entry.data)
+	}
+
+	return archive, nil
+}
@@ -0,0 +1,85 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func writeArchive(t *testing.T, archive *Archive) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := archive.WriteArchive(&buf); err != nil {
+		t.Fatalf("write archive: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	game := packSample(t, Options{})
+	tools := packSample(t, developmentOptions())
+	replacedLevel := packSample(t, Options{ChunkChecksums: true})
+
+	archive := NewArchive()
+	archive.AddPack("level", packSample(t, Options{BlockAlign: 64}))
+	archive.AddPack("game", game)
+	archive.AddPack("tools", tools)
+	archive.AddPack("level", replacedLevel)
+
+	opened, err := OpenArchive(bytes.NewReader(writeArchive(t, archive)))
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+
+	if names := opened.Names(); !reflect.DeepEqual(names, []string{"level", "game", "tools"}) {
+		t.Errorf("names are %q, expected the order they were first added in", names)
+	}
+
+	for name, expected := range map[string][]byte{"level": replacedLevel, "game": game, "tools": tools} {
+		data, wasFound := opened.Pack(name)
+		if !wasFound {
+			t.Fatalf("pack '%v' is missing", name)
+		}
+
+		if !bytes.Equal(data, expected) {
+			t.Errorf("pack '%v' differs from the pack that was added", name)
+		}
+	}
+
+	if _, wasFound := opened.Pack("missing"); wasFound {
+		t.Error("found a pack that was never added")
+	}
+}
+
+func TestArchiveManyPacks(t *testing.T) {
+	data := packSample(t, Options{})
+	archive := NewArchive()
+
+	for index := 0; index < DefaultLimits().MaxChunkCount+100; index++ {
+		archive.AddPack(fmt.Sprintf("pack%d", index), data)
+	}
+
+	opened, err := OpenArchive(bytes.NewReader(writeArchive(t, archive)))
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+
+	if len(opened.Names()) != len(archive.Names()) {
+		t.Errorf("opened %d packs, expected %d", len(opened.Names()), len(archive.Names()))
+	}
+}
+
+func TestOpenArchiveNotAnArchive(t *testing.T) {
+	if _, err := OpenArchive(bytes.NewReader(packSample(t, Options{}))); !errors.Is(err, ErrNotAnArchive) {
+		t.Fatalf("expected ErrNotAnArchive for a pack, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+)
+
+// PackBase64 is Pack encoded as standard base64, for embedding a pack in text formats.
+func PackBase64(ledger []byte, constantMemory []byte, typeInfo []byte) (string, error) {
+	octets, packErr := Pack(ledger, constantMemory, typeInfo)
+	if packErr != nil {
+		return "", packErr
+	}
+
+	return base64.StdEncoding.EncodeToString(octets), nil
+}
+
+// UnpackBase64 decodes a pack encoded by PackBase64.
+func UnpackBase64(s string) (*Contents, error) {
+	octets, decodeErr := base64.StdEncoding.DecodeString(s)
+	if decodeErr != nil {
+		return nil, fmt.Errorf("unpack base64 %w", decodeErr)
+	}
+
+	return Unpack(bytes.NewReader(octets))
+}
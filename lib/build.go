@@ -0,0 +1,95 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+const IconBuildMetadata raff.FourOctets = 0xF09F8F97 // 🏗
+
+var buildMetadataName = raff.MakeFourOctets('b', 'l', 'd', '0')
+
+// BuildMetadata records how a pack was built. It is only written when requested,
+// since it makes otherwise identical builds differ.
+// The Timestamp is stored as nanoseconds since the Unix epoch, so it must be between
+// 1677-09-21 and 2262-04-11, which also rules out the zero time.Time.
+type BuildMetadata struct {
+	Timestamp   time.Time
+	ToolVersion string
+}
+
+var (
+	minBuildTimestamp = time.Unix(0, math.MinInt64)
+	maxBuildTimestamp = time.Unix(0, math.MaxInt64)
+)
+
+func writeString(writer *bytes.Buffer, s string) error {
+	if len(s) > math.MaxUint16 {
+		return fmt.Errorf("string is too long (%d octets)", len(s))
+	}
+
+	binary.Write(writer, binary.BigEndian, uint16(len(s)))
+	writer.WriteString(s)
+
+	return nil
+}
+
+func readString(reader *bytes.Reader) (string, error) {
+	var octetCount uint16
+	if err := binary.Read(reader, binary.BigEndian, &octetCount); err != nil {
+		return "", err
+	}
+
+	octets := make([]byte, octetCount)
+	if _, err := io.ReadFull(reader, octets); err != nil {
+		return "", err
+	}
+
+	return string(octets), nil
+}
+
+func buildMetadataPayload(meta *BuildMetadata) ([]byte, error) {
+	if meta.Timestamp.Before(minBuildTimestamp) || meta.Timestamp.After(maxBuildTimestamp) {
+		return nil, fmt.Errorf("build metadata timestamp %v can not be stored as nanoseconds since 1970",
+			meta.Timestamp)
+	}
+
+	var payload bytes.Buffer
+
+	binary.Write(&payload, binary.BigEndian, meta.Timestamp.UnixNano())
+	if err := writeString(&payload, meta.ToolVersion); err != nil {
+		return nil, fmt.Errorf("build metadata tool version %w", err)
+	}
+
+	return payload.Bytes(), nil
+}
+
+func readBuildMetadata(payload []byte) (*BuildMetadata, error) {
+	reader := bytes.NewReader(payload)
+
+	var unixNano int64
+	if err := binary.Read(reader, binary.BigEndian, &unixNano); err != nil {
+		return nil, fmt.Errorf("build metadata timestamp %w", err)
+	}
+
+	toolVersion, toolVersionErr := readString(reader)
+	if toolVersionErr != nil {
+		return nil, fmt.Errorf("build metadata tool version %w", toolVersionErr)
+	}
+
+	return &BuildMetadata{
+		Timestamp:   time.Unix(0, unixNano).UTC(),
+		ToolVersion: toolVersion,
+	}, nil
+}
@@ -0,0 +1,61 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBuildMetadataRoundTrip(t *testing.T) {
+	meta := &BuildMetadata{Timestamp: time.Date(2023, 1, 17, 23, 35, 49, 123, time.UTC), ToolVersion: "swamp 0.1.2"}
+
+	contents := unpackData(t, packSample(t, Options{BuildMetadata: meta}))
+
+	checkSampleContents(t, contents)
+
+	if contents.BuildMetadata == nil {
+		t.Fatal("build metadata is missing")
+	}
+
+	if !contents.BuildMetadata.Timestamp.Equal(meta.Timestamp) || contents.BuildMetadata.ToolVersion != meta.ToolVersion {
+		t.Errorf("build metadata is %+v, expected %+v", *contents.BuildMetadata, *meta)
+	}
+}
+
+func TestBuildMetadataIsOptIn(t *testing.T) {
+	plain := packSample(t, Options{})
+	if contents := unpackData(t, plain); contents.BuildMetadata != nil {
+		t.Errorf("build metadata %+v was not requested", *contents.BuildMetadata)
+	}
+
+	stamped := packSample(t, Options{BuildMetadata: &BuildMetadata{Timestamp: time.Now(), ToolVersion: "x"}})
+	if !bytes.HasPrefix(stamped, plain) {
+		t.Error("build metadata changed the chunks before it")
+	}
+}
+
+func TestBuildMetadataTimestampRange(t *testing.T) {
+	for _, timestamp := range []time.Time{
+		{},
+		time.Date(1600, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		options := Options{BuildMetadata: &BuildMetadata{Timestamp: timestamp, ToolVersion: "v"}}
+		if _, err := PackWithOptions(sampleLedger, sampleConstantMemory, sampleTypeInfo, options); err == nil {
+			t.Errorf("expected an error for the timestamp %v", timestamp)
+		}
+	}
+
+	for _, timestamp := range []time.Time{time.Unix(0, math.MinInt64).UTC(), time.Unix(0, math.MaxInt64).UTC()} {
+		contents := unpackData(t, packSample(t, Options{BuildMetadata: &BuildMetadata{Timestamp: timestamp}}))
+		if !contents.BuildMetadata.Timestamp.Equal(timestamp) {
+			t.Errorf("timestamp is %v, expected %v", contents.BuildMetadata.Timestamp, timestamp)
+		}
+	}
+}
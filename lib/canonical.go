@@ -0,0 +1,150 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+// canonicalChunkOrder is the order PackWithOptions writes the known chunks in. Custom chunks
+// come after them, followed by the padding, the chunk index and the signature.
+var canonicalChunkOrder = []raff.FourOctets{
+	typeInfoName,
+	constantMemoryName,
+	ledgerName,
+	buildMetadataName,
+	instructionSetName,
+	sourceFilesName,
+	sourceHashName,
+	metadataName,
+	translationName,
+}
+
+func canonicalChunkRank(name raff.FourOctets) int {
+	name = referencedChunkName(name)
+
+	for index, canonicalName := range canonicalChunkOrder {
+		if name == canonicalName {
+			return index
+		}
+	}
+
+	switch name {
+	case paddingName:
+		return len(canonicalChunkOrder) + 1
+	case chunkIndexName:
+		return len(canonicalChunkOrder) + 2
+	case signatureName:
+		return len(canonicalChunkOrder) + 3
+	default:
+		return len(canonicalChunkOrder)
+	}
+}
+
+// storedChunks returns the pack header chunk and every chunk after it as stored, including dead chunks.
+func storedChunks(data []byte) (Chunk, []Chunk, error) {
+	reader := &sliceReader{octets: data}
+	if err := readFileHeader(reader); err != nil {
+		return Chunk{}, nil, err
+	}
+
+	chunkReader := newLimitedChunkReader(reader, Limits{})
+
+	packHeader, packHeaderPayload, packHeaderErr := chunkReader.readChunk()
+	if packHeaderErr != nil {
+		return Chunk{}, nil, fmt.Errorf("read pack header %w", packHeaderErr)
+	}
+
+	var chunks []Chunk
+
+	for {
+		header, payload, readErr := chunkReader.readChunk()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+
+		if readErr != nil {
+			return Chunk{}, nil, fmt.Errorf("read chunk %w", readErr)
+		}
+
+		chunks = append(chunks, Chunk{Icon: header.Icon, Name: header.Name, Payload: payload})
+	}
+
+	return Chunk{Icon: packHeader.Icon, Name: packHeader.Name, Payload: packHeaderPayload}, chunks, nil
+}
+
+func isCanonicalChunkOrder(a Chunk, b Chunk) bool {
+	return canonicalChunkRank(a.Name) < canonicalChunkRank(b.Name)
+}
+
+// writeStoredChunks writes a pack with the stored chunks as they are. Signatures and chunk indices
+// are left out, since they would no longer match.
+func writeStoredChunks(packHeader Chunk, chunks []Chunk) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := raff.WriteHeader(&buf); err != nil {
+		return nil, fmt.Errorf("write header %w", err)
+	}
+
+	if err := writeChunkHeader(&buf, packHeader.Icon, packHeader.Name, packHeader.Payload); err != nil {
+		return nil, err
+	}
+
+	for _, chunk := range chunks {
+		if chunk.Name == signatureName || chunk.Name == chunkIndexName {
+			continue
+		}
+
+		if err := writeChunkHeader(&buf, chunk.Icon, chunk.Name, chunk.Payload); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Canonicalize returns a copy of the pack with dead chunks removed and the chunks in the order
+// PackWithOptions writes them. Payloads, checksums and compression are kept as they are, so a pack
+// that is already canonical comes back unchanged. A signature no longer matches once chunks have
+// been moved or removed, so it is dropped in that case and the result has to be signed again.
+// A chunk index is dropped for the same reason.
+func Canonicalize(data []byte) ([]byte, error) {
+	if err := Verify(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	packHeader, allChunks, readErr := storedChunks(data)
+	if readErr != nil {
+		return nil, fmt.Errorf("canonicalize %w", readErr)
+	}
+
+	chunks := make([]Chunk, 0, len(allChunks))
+	for _, chunk := range allChunks {
+		if chunk.Icon != IconDead {
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	isCanonical := len(chunks) == len(allChunks) && sort.SliceIsSorted(chunks, func(a, b int) bool {
+		return isCanonicalChunkOrder(chunks[a], chunks[b])
+	})
+
+	if isCanonical {
+		return append([]byte(nil), data...), nil
+	}
+
+	sort.SliceStable(chunks, func(a, b int) bool {
+		return isCanonicalChunkOrder(chunks[a], chunks[b])
+	})
+
+	return writeStoredChunks(packHeader, chunks)
+}
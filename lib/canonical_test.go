@@ -0,0 +1,58 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCanonicalizeCanonicalPack(t *testing.T) {
+	for _, options := range []Options{{}, developmentOptions(), {ChunkChecksums: true, LedgerCompression: CompressionZlib}} {
+		data := packSample(t, options)
+
+		canonical, err := Canonicalize(data)
+		if err != nil {
+			t.Fatalf("canonicalize: %v", err)
+		}
+
+		if !bytes.Equal(canonical, data) {
+			t.Errorf("canonicalizing a canonical pack changed it")
+		}
+	}
+}
+
+func TestCanonicalizeNonCanonicalPack(t *testing.T) {
+	data := packSample(t, developmentOptions())
+
+	packHeader, chunks, err := storedChunks(data)
+	if err != nil {
+		t.Fatalf("stored chunks: %v", err)
+	}
+
+	reordered := []Chunk{{Icon: IconDead, Name: ledgerName, Payload: []byte{0xde, 0xad}}}
+	for index := len(chunks) - 1; index >= 0; index-- {
+		reordered = append(reordered, chunks[index])
+	}
+
+	nonCanonical, writeErr := writeStoredChunks(packHeader, reordered)
+	if writeErr != nil {
+		t.Fatalf("write stored chunks: %v", writeErr)
+	}
+
+	if bytes.Equal(nonCanonical, data) {
+		t.Fatal("the reordered pack should differ from the canonical pack")
+	}
+
+	canonical, canonicalErr := Canonicalize(nonCanonical)
+	if canonicalErr != nil {
+		t.Fatalf("canonicalize: %v", canonicalErr)
+	}
+
+	if !bytes.Equal(canonical, data) {
+		t.Errorf("canonicalized pack differs from the pack written in canonical order")
+	}
+}
@@ -0,0 +1,54 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+const chunkChecksumOctetCount = 4
+
+var ErrChunkChecksum = errors.New("chunk checksum mismatch")
+
+// sealChunkPayload copies payload into sealed, followed by its checksum.
+func sealChunkPayload(sealed []byte, payload []byte) {
+	copy(sealed, payload)
+	binary.BigEndian.PutUint32(sealed[len(payload):], crc32.ChecksumIEEE(payload))
+}
+
+// checkedPayload removes the checksum from a chunk payload when the pack header has the chunk
+// checksums flag. On a checksum error it still returns the payload without the checksum octets,
+// so callers that collect problems can go on reading.
+func (r *limitedChunkReader) checkedPayload(header raff.ChunkHeader, payload []byte) ([]byte, error) {
+	if r.flags&packFlagChunkChecksums == 0 {
+		return payload, nil
+	}
+
+	strippedPayload, checksumErr := stripChunkChecksum(header, payload)
+	if checksumErr != nil && len(payload) >= chunkChecksumOctetCount {
+		strippedPayload = payload[:len(payload)-chunkChecksumOctetCount]
+	}
+
+	return strippedPayload, checksumErr
+}
+
+func stripChunkChecksum(header raff.ChunkHeader, payload []byte) ([]byte, error) {
+	if len(payload) < chunkChecksumOctetCount {
+		return nil, fmt.Errorf("%w: '%v' is too short for a checksum", ErrChunkChecksum, raff.NameToString(header.Name))
+	}
+
+	payloadOctetCount := len(payload) - chunkChecksumOctetCount
+	if binary.BigEndian.Uint32(payload[payloadOctetCount:]) != crc32.ChecksumIEEE(payload[:payloadOctetCount]) {
+		return nil, fmt.Errorf("%w: '%v'", ErrChunkChecksum, raff.NameToString(header.Name))
+	}
+
+	return payload[:payloadOctetCount], nil
+}
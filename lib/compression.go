@@ -0,0 +1,169 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+// Compression selects how a section is compressed. The chosen value is stored as the first
+// octet of the compressed chunk payload, and selects the Codec used to decompress it.
+type Compression uint8
+
+const (
+	CompressionNone Compression = iota
+	CompressionZlib
+)
+
+// Codec compresses chunk payloads for a Compression registered with RegisterCodec.
+// Decompress should stop once the output is larger than maxOctetCount.
+type Codec interface {
+	Compress(payload []byte) ([]byte, error)
+	Decompress(compressed []byte, maxOctetCount uint32) ([]byte, error)
+}
+
+var (
+	ErrUnknownCodec              = errors.New("unknown compression codec")
+	compressedConstantMemoryName = raff.MakeFourOctets('d', 'm', 'z', '1')
+	compressedLedgerName         = raff.MakeFourOctets('l', 'd', 'z', '0')
+	codecsLock                   sync.RWMutex
+	codecs                       = map[Compression]Codec{CompressionNone: storeCodec{}, CompressionZlib: zlibCodec{}}
+)
+
+// RegisterCodec makes codec available for the compression id, usually from an init function.
+// It panics if id is CompressionNone, if codec is nil or if id is already registered.
+func RegisterCodec(id Compression, codec Codec) {
+	if id == CompressionNone {
+		panic("swamppack: RegisterCodec can not register CompressionNone")
+	}
+
+	if codec == nil {
+		panic("swamppack: RegisterCodec codec is nil")
+	}
+
+	codecsLock.Lock()
+	defer codecsLock.Unlock()
+
+	if _, wasRegistered := codecs[id]; wasRegistered {
+		panic(fmt.Sprintf("swamppack: RegisterCodec called twice for compression %d", id))
+	}
+
+	codecs[id] = codec
+}
+
+func lookupCodec(id Compression) (Codec, bool) {
+	codecsLock.RLock()
+	defer codecsLock.RUnlock()
+
+	codec, wasFound := codecs[id]
+
+	return codec, wasFound
+}
+
+// storeCodec keeps the payload as it is. PackWithOptions writes an uncompressed chunk for CompressionNone
+// instead, but other writers may store a section in a compressed chunk without compressing it.
+type storeCodec struct{}
+
+func (storeCodec) Compress(payload []byte) ([]byte, error) {
+	return payload, nil
+}
+
+func (storeCodec) Decompress(compressed []byte, maxOctetCount uint32) ([]byte, error) {
+	return compressed, nil
+}
+
+type zlibCodec struct{}
+
+func (zlibCodec) Compress(payload []byte) ([]byte, error) {
+	var compressed bytes.Buffer
+
+	writer := zlib.NewWriter(&compressed)
+	if _, err := writer.Write(payload); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return compressed.Bytes(), nil
+}
+
+func (zlibCodec) Decompress(compressed []byte, maxOctetCount uint32) ([]byte, error) {
+	reader, readerErr := zlib.NewReader(bytes.NewReader(compressed))
+	if readerErr != nil {
+		return nil, readerErr
+	}
+
+	return io.ReadAll(io.LimitReader(reader, int64(maxOctetCount)+1))
+}
+
+// compressedChunkNames maps the name of a compressed chunk to the name of its uncompressed form.
+var compressedChunkNames = map[raff.FourOctets]raff.FourOctets{
+	compressedConstantMemoryName: constantMemoryName,
+	compressedLedgerName:         ledgerName,
+}
+
+func uncompressedChunkName(name raff.FourOctets) raff.FourOctets {
+	if uncompressedName, isCompressed := compressedChunkNames[name]; isCompressed {
+		return uncompressedName
+	}
+
+	return name
+}
+
+func compressChunk(chunk Chunk, compressedName raff.FourOctets, compression Compression) (Chunk, error) {
+	if compression == CompressionNone {
+		return chunk, nil
+	}
+
+	codec, wasFound := lookupCodec(compression)
+	if !wasFound {
+		return Chunk{}, fmt.Errorf("%w: %d for '%v'", ErrUnknownCodec, compression, raff.NameToString(chunk.Name))
+	}
+
+	compressed, compressErr := codec.Compress(chunk.Payload)
+	if compressErr != nil {
+		return Chunk{}, fmt.Errorf("compress '%v' %w", raff.NameToString(chunk.Name), compressErr)
+	}
+
+	payload := make([]byte, 1+len(compressed))
+	payload[0] = byte(compression)
+	copy(payload[1:], compressed)
+
+	return Chunk{Icon: chunk.Icon, Name: compressedName, Payload: payload}, nil
+}
+
+func decompressChunkPayload(header raff.ChunkHeader, payload []byte, maxOctetCount uint32) ([]byte, error) {
+	if len(payload) == 0 {
+		return nil, fmt.Errorf("compressed chunk '%v' is empty", raff.NameToString(header.Name))
+	}
+
+	codec, wasFound := lookupCodec(Compression(payload[0]))
+	if !wasFound {
+		return nil, fmt.Errorf("%w: compressed chunk '%v' uses %d", ErrUnknownCodec,
+			raff.NameToString(header.Name), payload[0])
+	}
+
+	inflated, inflateErr := codec.Decompress(payload[1:], maxOctetCount)
+	if inflateErr != nil {
+		return nil, fmt.Errorf("decompress '%v' %w", raff.NameToString(header.Name), inflateErr)
+	}
+
+	if len(inflated) > int(maxOctetCount) {
+		return nil, fmt.Errorf("%w: '%v' decompresses to more than %d octets", ErrChunkTooLarge,
+			raff.NameToString(header.Name), maxOctetCount)
+	}
+
+	return inflated, nil
+}
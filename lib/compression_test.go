@@ -0,0 +1,205 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+const compressionXor Compression = 0x80
+
+// xorCodec is a trivial codec that flips every octet, to check that registered codecs are used.
+type xorCodec struct{}
+
+func xorOctets(octets []byte) []byte {
+	result := make([]byte, len(octets))
+	for index, octet := range octets {
+		result[index] = octet ^ 0x5a
+	}
+
+	return result
+}
+
+func (xorCodec) Compress(payload []byte) ([]byte, error) {
+	return xorOctets(payload), nil
+}
+
+func (xorCodec) Decompress(compressed []byte, maxOctetCount uint32) ([]byte, error) {
+	if uint32(len(compressed)) > maxOctetCount {
+		return nil, fmt.Errorf("xor payload is larger than %d octets", maxOctetCount)
+	}
+
+	return xorOctets(compressed), nil
+}
+
+// The codec is registered once for the test binary, since RegisterCodec panics on a second call.
+func init() {
+	RegisterCodec(compressionXor, xorCodec{})
+}
+
+// representativeSections returns a ledger of short opcodes with small operands and a constant
+// memory of identifier-like strings, which compress very differently.
+func representativeSections() (ledger []byte, constantMemory []byte) {
+	random := rand.New(rand.NewSource(1))
+
+	for len(ledger) < 256*1024 {
+		ledger = append(ledger, byte(random.Intn(48)), byte(random.Intn(16)), byte(random.Intn(256)))
+	}
+
+	var constants bytes.Buffer
+	for index := 0; constants.Len() < 128*1024; index++ {
+		fmt.Fprintf(&constants, "player.inventory.slot%d.item_%d\x00", index%64, random.Intn(1000))
+	}
+
+	return ledger, constants.Bytes()
+}
+
+func TestSplitCompressionRoundTrip(t *testing.T) {
+	ledger, constantMemory := representativeSections()
+
+	for _, options := range []Options{
+		{LedgerCompression: CompressionZlib},
+		{ConstantMemoryCompression: CompressionZlib},
+		{LedgerCompression: CompressionZlib, ConstantMemoryCompression: CompressionZlib, ChunkChecksums: true},
+	} {
+		data, err := PackWithOptions(ledger, constantMemory, sampleTypeInfo, options)
+		if err != nil {
+			t.Fatalf("pack: %v", err)
+		}
+
+		contents := unpackData(t, data)
+		if !bytes.Equal(contents.Ledger, ledger) || !bytes.Equal(contents.ConstantMemory, constantMemory) {
+			t.Errorf("compressed sections did not survive a round trip with %+v", options)
+		}
+
+		if err := VerifyStream(bytes.NewReader(data), DefaultLimits()); err != nil {
+			t.Errorf("verify stream: %v", err)
+		}
+	}
+}
+
+func BenchmarkCompression(b *testing.B) {
+	ledger, constantMemory := representativeSections()
+
+	b.Run("combined", func(b *testing.B) {
+		b.ReportAllocs()
+
+		var octetCount int
+
+		for i := 0; i < b.N; i++ {
+			combined, err := zlibCodec{}.Compress(append(append([]byte{}, ledger...), constantMemory...))
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			octetCount = len(combined)
+		}
+
+		b.ReportMetric(float64(octetCount), "octets")
+	})
+
+	b.Run("split", func(b *testing.B) {
+		b.ReportAllocs()
+
+		options := Options{LedgerCompression: CompressionZlib, ConstantMemoryCompression: CompressionZlib}
+
+		var octetCount int
+
+		for i := 0; i < b.N; i++ {
+			data, err := PackWithOptions(ledger, constantMemory, sampleTypeInfo, options)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			octetCount = len(data)
+		}
+
+		b.ReportMetric(float64(octetCount), "octets")
+	})
+}
+
+func TestRegisteredCodecRoundTrip(t *testing.T) {
+	data := packSample(t, Options{LedgerCompression: compressionXor, ConstantMemoryCompression: compressionXor})
+
+	_, chunks, err := storedChunks(data)
+	if err != nil {
+		t.Fatalf("stored chunks: %v", err)
+	}
+
+	ledgerPayload := findChunkPayload(chunks, compressedLedgerName)
+	if !bytes.Equal(ledgerPayload, append([]byte{byte(compressionXor)}, xorOctets(sampleLedger)...)) {
+		t.Errorf("ldz0 payload is %x, expected the codec id and the xor of the ledger", ledgerPayload)
+	}
+
+	checkSampleContents(t, unpackData(t, data))
+}
+
+func TestUnknownCodec(t *testing.T) {
+	if _, err := PackWithOptions(sampleLedger, sampleConstantMemory, sampleTypeInfo,
+		Options{LedgerCompression: 0x7f}); !errors.Is(err, ErrUnknownCodec) {
+		t.Fatalf("pack: expected ErrUnknownCodec, got %v", err)
+	}
+
+	data := packSample(t, Options{LedgerCompression: compressionXor})
+
+	codecOffset := bytes.Index(data, append([]byte{byte(compressionXor)}, xorOctets(sampleLedger)...))
+	if codecOffset < 0 {
+		t.Fatal("compressed ledger not found")
+	}
+
+	data[codecOffset] = 0x7f
+
+	if _, err := Unpack(bytes.NewReader(data)); !errors.Is(err, ErrUnknownCodec) {
+		t.Fatalf("unpack: expected ErrUnknownCodec, got %v", err)
+	}
+}
+
+func TestRegisterCodecPanics(t *testing.T) {
+	for _, id := range []Compression{CompressionNone, CompressionZlib, compressionXor} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterCodec(%d) did not panic", id)
+				}
+			}()
+
+			RegisterCodec(id, xorCodec{})
+		}()
+	}
+}
+
+func TestStoredCompressedChunk(t *testing.T) {
+	packHeader, chunks, err := storedChunks(packSample(t, Options{ConstantMemoryCompression: CompressionZlib}))
+	if err != nil {
+		t.Fatalf("stored chunks: %v", err)
+	}
+
+	for index, chunk := range chunks {
+		if chunk.Name == compressedConstantMemoryName {
+			chunks[index].Payload = append([]byte{byte(CompressionNone)}, sampleConstantMemory...)
+		}
+	}
+
+	data, writeErr := writeStoredChunks(packHeader, chunks)
+	if writeErr != nil {
+		t.Fatalf("write stored chunks: %v", writeErr)
+	}
+
+	checkSampleContents(t, unpackData(t, data))
+
+	_, plainChunks, plainErr := storedChunks(packSample(t, Options{ConstantMemoryCompression: CompressionNone}))
+	if plainErr != nil {
+		t.Fatalf("stored chunks: %v", plainErr)
+	}
+
+	if findChunkPayload(plainChunks, compressedConstantMemoryName) != nil {
+		t.Error("CompressionNone should write an uncompressed dme1 chunk")
+	}
+}
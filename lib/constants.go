@@ -0,0 +1,64 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrNoConstantMemory = errors.New("pack has no constant memory chunk")
+
+// UnpackConstantMemory returns only the constant memory payload, skipping over all other chunks.
+func UnpackConstantMemory(data []byte) ([]byte, error) {
+	chunkReader := newLimitedChunkReader(bytes.NewReader(data), Limits{})
+
+	_, flagsErr := readPackHeader(chunkReader)
+	if flagsErr != nil {
+		return nil, flagsErr
+	}
+
+	for {
+		header, headerErr := chunkReader.readChunkHeader()
+		if errors.Is(headerErr, io.EOF) {
+			return nil, ErrNoConstantMemory
+		}
+
+		if headerErr != nil {
+			return nil, fmt.Errorf("unpack constant memory read chunk %w", headerErr)
+		}
+
+		if uncompressedChunkName(header.Name) != constantMemoryName || header.Icon == IconDead {
+			if err := chunkReader.skipChunkPayload(header); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if err := checkIcon(header, IconConstantMemory); err != nil {
+			return nil, err
+		}
+
+		payload, payloadErr := readChunkPayload(chunkReader.reader, header)
+		if payloadErr != nil {
+			return nil, payloadErr
+		}
+
+		checkedPayload, checksumErr := chunkReader.checkedPayload(header, payload)
+		if checksumErr != nil {
+			return nil, checksumErr
+		}
+
+		if header.Name == compressedConstantMemoryName {
+			return decompressChunkPayload(header, checkedPayload, chunkReader.limits.MaxChunkOctetCount)
+		}
+
+		return checkedPayload, nil
+	}
+}
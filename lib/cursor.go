@@ -0,0 +1,88 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Cursor steps through the chunks of a pack in memory, in the style of bufio.Scanner:
+//
+//	cursor := NewCursor(data)
+//	for chunk, ok := cursor.Next(); ok; chunk, ok = cursor.Next() {
+//		...
+//	}
+//	if err := cursor.Err(); err != nil {
+//		...
+//	}
+//
+// The chunks are returned as stored, including dead chunks, checksums and compressed payloads.
+// Payloads refer to data and are not copied.
+type Cursor struct {
+	source      sliceReader
+	chunkReader *limitedChunkReader
+	version     Version
+	err         error
+	isStarted   bool
+	isDone      bool
+}
+
+func NewCursor(data []byte) *Cursor {
+	cursor := &Cursor{source: sliceReader{octets: data}}
+	cursor.chunkReader = newLimitedChunkReader(&cursor.source, Limits{})
+
+	return cursor
+}
+
+// Next returns the next chunk after the pack header. It returns false at the end of the pack or
+// at the first problem, which is then returned by Err.
+func (c *Cursor) Next() (Chunk, bool) {
+	if c.isDone {
+		return Chunk{}, false
+	}
+
+	if !c.isStarted {
+		c.isStarted = true
+
+		version, _, headerErr := readPackHeaderChunk(c.chunkReader)
+		if headerErr != nil {
+			return c.fail(headerErr)
+		}
+
+		c.version = version
+	}
+
+	header, payload, readErr := c.chunkReader.readChunk()
+	if errors.Is(readErr, io.EOF) {
+		c.isDone = true
+		return Chunk{}, false
+	}
+
+	if readErr != nil {
+		return c.fail(fmt.Errorf("cursor read chunk %w", readErr))
+	}
+
+	return Chunk{Icon: header.Icon, Name: header.Name, Payload: payload}, true
+}
+
+func (c *Cursor) fail(err error) (Chunk, bool) {
+	c.err = err
+	c.isDone = true
+
+	return Chunk{}, false
+}
+
+// Version is the version from the pack header. It is only valid after the first call to Next.
+func (c *Cursor) Version() Version {
+	return c.version
+}
+
+// Err returns the first problem found by Next, or nil if the end of the pack was reached.
+func (c *Cursor) Err() error {
+	return c.err
+}
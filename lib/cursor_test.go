@@ -0,0 +1,84 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+func TestCursor(t *testing.T) {
+	cursor := NewCursor(packSample(t, Options{}))
+
+	expected := []Chunk{
+		{Icon: IconTypeInfo, Name: typeInfoName, Payload: sampleTypeInfo},
+		{Icon: IconConstantMemory, Name: constantMemoryName, Payload: sampleConstantMemory},
+		{Icon: IconLedger, Name: ledgerName, Payload: sampleLedger},
+	}
+
+	var chunks []Chunk
+	for chunk, ok := cursor.Next(); ok; chunk, ok = cursor.Next() {
+		chunks = append(chunks, chunk)
+	}
+
+	if err := cursor.Err(); err != nil {
+		t.Fatalf("cursor: %v", err)
+	}
+
+	if cursor.Version() != CurrentVersion {
+		t.Errorf("version is %v, expected %v", cursor.Version(), CurrentVersion)
+	}
+
+	if len(chunks) != len(expected) {
+		t.Fatalf("cursor returned %d chunks, expected %d", len(chunks), len(expected))
+	}
+
+	for index, chunk := range chunks {
+		if chunk.Icon != expected[index].Icon || chunk.Name != expected[index].Name ||
+			!bytes.Equal(chunk.Payload, expected[index].Payload) {
+			t.Errorf("chunk %d is '%v' %x, expected '%v' %x", index, raff.NameToString(chunk.Name), chunk.Payload,
+				raff.NameToString(expected[index].Name), expected[index].Payload)
+		}
+	}
+
+	if _, ok := cursor.Next(); ok {
+		t.Error("Next returned a chunk after the end")
+	}
+}
+
+func TestCursorTruncated(t *testing.T) {
+	data := packSample(t, Options{})
+	cursor := NewCursor(data[:len(data)-2])
+
+	count := 0
+	for _, ok := cursor.Next(); ok; _, ok = cursor.Next() {
+		count++
+	}
+
+	if count != 2 {
+		t.Errorf("cursor returned %d chunks before the truncated one, expected 2", count)
+	}
+
+	if err := cursor.Err(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestCursorNotAPack(t *testing.T) {
+	cursor := NewCursor([]byte("not a pack at all"))
+
+	if _, ok := cursor.Next(); ok {
+		t.Fatal("Next returned a chunk")
+	}
+
+	if err := cursor.Err(); !errors.Is(err, ErrNotASwampPack) {
+		t.Errorf("expected ErrNotASwampPack, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+// DiffOptions changes what DiffPacks compares. The zero value compares all chunks.
+type DiffOptions struct {
+	// IgnoreChunks are left out of the comparison, e.g. bld0 and src0 when comparing build variants.
+	IgnoreChunks []raff.FourOctets
+}
+
+// logicalChunks returns the live chunks after the pack header, with checksums removed and
+// compressed chunks decompressed under their uncompressed name.
+func logicalChunks(data []byte) (Version, []Chunk, error) {
+	chunkReader := newLimitedChunkReader(bytes.NewReader(data), Limits{})
+
+	version, _, headerErr := readPackHeaderChunk(chunkReader)
+	if headerErr != nil {
+		return Version{}, nil, headerErr
+	}
+
+	var chunks []Chunk
+
+	for {
+		header, payload, readErr := chunkReader.readChunk()
+		if errors.Is(readErr, io.EOF) {
+			return version, chunks, nil
+		}
+
+		if readErr != nil {
+			return Version{}, nil, fmt.Errorf("read chunk %w", readErr)
+		}
+
+		if header.Icon == IconDead {
+			continue
+		}
+
+		checkedPayload, checksumErr := chunkReader.checkedPayload(header, payload)
+		if checksumErr != nil {
+			return Version{}, nil, checksumErr
+		}
+
+		payload = checkedPayload
+
+		if uncompressedName, isCompressed := compressedChunkNames[header.Name]; isCompressed {
+			inflated, inflateErr := decompressChunkPayload(header, payload, chunkReader.limits.MaxChunkOctetCount)
+			if inflateErr != nil {
+				return Version{}, nil, inflateErr
+			}
+
+			header.Name = uncompressedName
+			payload = inflated
+		}
+
+		chunks = append(chunks, Chunk{Icon: header.Icon, Name: header.Name, Payload: payload})
+	}
+}
+
+func chunksByName(chunks []Chunk, ignore []raff.FourOctets) (map[raff.FourOctets][][]byte, []raff.FourOctets) {
+	payloads := make(map[raff.FourOctets][][]byte)
+
+	var names []raff.FourOctets
+
+	for _, chunk := range chunks {
+		isIgnored := false
+		for _, name := range ignore {
+			if chunk.Name == name {
+				isIgnored = true
+				break
+			}
+		}
+
+		if isIgnored {
+			continue
+		}
+
+		if _, wasSeen := payloads[chunk.Name]; !wasSeen {
+			names = append(names, chunk.Name)
+		}
+
+		payloads[chunk.Name] = append(payloads[chunk.Name], chunk.Payload)
+	}
+
+	return payloads, names
+}
+
+// DiffPacks compares the chunks of two packs by name and payload and describes each difference.
+// Checksums, compression and chunk order are not considered differences. An empty result means
+// the packs are equivalent.
+func DiffPacks(a []byte, b []byte, options DiffOptions) ([]string, error) {
+	versionA, chunksA, errA := logicalChunks(a)
+	if errA != nil {
+		return nil, fmt.Errorf("diff packs a %w", errA)
+	}
+
+	versionB, chunksB, errB := logicalChunks(b)
+	if errB != nil {
+		return nil, fmt.Errorf("diff packs b %w", errB)
+	}
+
+	var differences []string
+
+	if versionA != versionB {
+		differences = append(differences, fmt.Sprintf("version %v vs %v", versionA, versionB))
+	}
+
+	payloadsA, namesA := chunksByName(chunksA, options.IgnoreChunks)
+	payloadsB, namesB := chunksByName(chunksB, options.IgnoreChunks)
+
+	for _, name := range namesA {
+		nameString := raff.NameToString(name)
+
+		payloadListB, isInB := payloadsB[name]
+		if !isInB {
+			differences = append(differences, fmt.Sprintf("chunk '%v' only in a", nameString))
+			continue
+		}
+
+		payloadListA := payloadsA[name]
+		if len(payloadListA) != len(payloadListB) {
+			differences = append(differences, fmt.Sprintf("chunk '%v' occurs %d vs %d times", nameString,
+				len(payloadListA), len(payloadListB)))
+			continue
+		}
+
+		for index, payloadA := range payloadListA {
+			if payloadB := payloadListB[index]; !bytes.Equal(payloadA, payloadB) {
+				differences = append(differences, fmt.Sprintf("chunk '%v' differs (%d vs %d octets)", nameString,
+					len(payloadA), len(payloadB)))
+			}
+		}
+	}
+
+	for _, name := range namesB {
+		if _, isInA := payloadsA[name]; !isInA {
+			differences = append(differences, fmt.Sprintf("chunk '%v' only in b", raff.NameToString(name)))
+		}
+	}
+
+	return differences, nil
+}
@@ -0,0 +1,67 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"testing"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+func TestDiffPacksIgnoreChunks(t *testing.T) {
+	release := packSample(t, Options{})
+	development := packSample(t, developmentOptions())
+
+	differences, err := DiffPacks(development, release, DiffOptions{})
+	if err != nil {
+		t.Fatalf("diff packs: %v", err)
+	}
+
+	if len(differences) != 3 {
+		t.Errorf("expected the three debug chunks to differ, got %q", differences)
+	}
+
+	ignore := DiffOptions{IgnoreChunks: []raff.FourOctets{buildMetadataName, sourceFilesName, metadataName}}
+
+	ignoredDifferences, ignoredErr := DiffPacks(development, release, ignore)
+	if ignoredErr != nil {
+		t.Fatalf("diff packs: %v", ignoredErr)
+	}
+
+	if len(ignoredDifferences) != 0 {
+		t.Errorf("expected no differences when ignoring debug chunks, got %q", ignoredDifferences)
+	}
+}
+
+func TestDiffPacksEncoding(t *testing.T) {
+	plain := packSample(t, Options{})
+	encoded := packSample(t, Options{ChunkChecksums: true, LedgerCompression: CompressionZlib, BlockAlign: 64})
+
+	differences, err := DiffPacks(plain, encoded, DiffOptions{IgnoreChunks: []raff.FourOctets{paddingName}})
+	if err != nil {
+		t.Fatalf("diff packs: %v", err)
+	}
+
+	if len(differences) != 0 {
+		t.Errorf("checksums and compression should not be differences, got %q", differences)
+	}
+}
+
+func TestDiffPacksLedger(t *testing.T) {
+	other, err := PackWithOptions([]byte{0x09}, sampleConstantMemory, sampleTypeInfo, Options{})
+	if err != nil {
+		t.Fatalf("pack: %v", err)
+	}
+
+	differences, diffErr := DiffPacks(packSample(t, Options{}), other, DiffOptions{})
+	if diffErr != nil {
+		t.Fatalf("diff packs: %v", diffErr)
+	}
+
+	if len(differences) != 1 {
+		t.Errorf("expected only the ledger to differ, got %q", differences)
+	}
+}
@@ -0,0 +1,117 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Operand describes one big-endian operand that follows an opcode. Alignment is only needed by
+// VerifyOperandAlignment; zero means the operand can start at any offset.
+type Operand struct {
+	OctetCount  int
+	ConstantRef bool
+	Alignment   int
+}
+
+// Instruction describes an opcode and the operands that follow it. StackPops and StackPushes are
+// the stack effect of the opcode, and are only needed by VerifyStackBalance.
+type Instruction struct {
+	Mnemonic    string
+	Operands    []Operand
+	StackPops   int
+	StackPushes int
+}
+
+// OpcodeTable maps an opcode octet to its instruction, so callers can supply the instruction set of their VM.
+type OpcodeTable map[byte]Instruction
+
+type decodedInstruction struct {
+	offset      int
+	opcode      byte
+	instruction Instruction
+	values      []uint32
+	octetCount  int
+}
+
+func readOperandValue(octets []byte) uint32 {
+	var value uint32
+	for _, octet := range octets {
+		value = value<<8 | uint32(octet)
+	}
+
+	return value
+}
+
+func decodeInstruction(opcodes []byte, offset int, table OpcodeTable) (decodedInstruction, error) {
+	opcode := opcodes[offset]
+
+	instruction, wasFound := table[opcode]
+	if !wasFound {
+		return decodedInstruction{}, fmt.Errorf("unknown opcode %02X at offset %04X", opcode, offset)
+	}
+
+	decoded := decodedInstruction{offset: offset, opcode: opcode, instruction: instruction, octetCount: 1}
+
+	for _, operand := range instruction.Operands {
+		if operand.OctetCount < 1 || operand.OctetCount > 4 {
+			return decodedInstruction{}, fmt.Errorf("opcode %02X '%v' has an operand of %d octets",
+				opcode, instruction.Mnemonic, operand.OctetCount)
+		}
+
+		operandOffset := offset + decoded.octetCount
+		if operandOffset+operand.OctetCount > len(opcodes) {
+			return decodedInstruction{}, fmt.Errorf("opcode %02X '%v' at offset %04X is truncated",
+				opcode, instruction.Mnemonic, offset)
+		}
+
+		decoded.values = append(decoded.values, readOperandValue(opcodes[operandOffset:operandOffset+operand.OctetCount]))
+		decoded.octetCount += operand.OctetCount
+	}
+
+	return decoded, nil
+}
+
+func forEachInstruction(opcodes []byte, table OpcodeTable, handle func(decodedInstruction) error) error {
+	for offset := 0; offset < len(opcodes); {
+		decoded, decodeErr := decodeInstruction(opcodes, offset, table)
+		if decodeErr != nil {
+			return decodeErr
+		}
+
+		if err := handle(decoded); err != nil {
+			return err
+		}
+
+		offset += decoded.octetCount
+	}
+
+	return nil
+}
+
+// DisassembleOpcodes writes one line per instruction with its offset, mnemonic and operands.
+// Constant reference operands are written as #index.
+func DisassembleOpcodes(opcodes []byte, table OpcodeTable, writer io.Writer) error {
+	return forEachInstruction(opcodes, table, func(decoded decodedInstruction) error {
+		operandStrings := make([]string, len(decoded.values))
+		for index, value := range decoded.values {
+			if decoded.instruction.Operands[index].ConstantRef {
+				operandStrings[index] = fmt.Sprintf("#%d", value)
+			} else {
+				operandStrings[index] = fmt.Sprintf("%d", value)
+			}
+		}
+
+		line := fmt.Sprintf("%04X  %-12s %v", decoded.offset, decoded.instruction.Mnemonic,
+			strings.Join(operandStrings, ", "))
+
+		_, err := fmt.Fprintln(writer, strings.TrimRight(line, " "))
+
+		return err
+	})
+}
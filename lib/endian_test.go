@@ -0,0 +1,128 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fixedPack is the sample packed with checksums, an instruction set, build metadata and metadata.
+// Every multi-octet field is big-endian, so decoding it must give the same values on any GOARCH.
+var fixedPack = []byte{
+	0xf0, 0x9f, 0xa6, 0x8a, 0x52, 0x41, 0x46, 0x46, 0x0a, // RAFF file header
+	0xf0, 0x9f, 0x93, 0xa6, 0x73, 0x70, 0x6b, 0x35, 0x00, 0x00, 0x00, 0x05, // spk5, 5 octets
+	0x00, 0x00, 0x00, 0x00, 0x01, // minor 0, flags 00000001
+	0xf0, 0x9f, 0x93, 0x9c, 0x73, 0x74, 0x69, 0x30, 0x00, 0x00, 0x00, 0x06, // sti0, 6 octets
+	0xca, 0xfe, 0x2a, 0xc9, 0xf2, 0x20,
+	0xf0, 0x9f, 0x92, 0xbb, 0x64, 0x6d, 0x65, 0x31, 0x00, 0x00, 0x00, 0x07, // dme1, 7 octets
+	0x10, 0x20, 0x30, 0x50, 0x3a, 0x6e, 0x6c,
+	0xf0, 0x9f, 0x97, 0x92, 0x6c, 0x64, 0x67, 0x30, 0x00, 0x00, 0x00, 0x09, // ldg0, 9 octets
+	0x01, 0x02, 0x03, 0x04, 0x05, 0x47, 0x0b, 0x99, 0xf4,
+	0xf0, 0x9f, 0x8f, 0x97, 0x62, 0x6c, 0x64, 0x30, 0x00, 0x00, 0x00, 0x10, // bld0, 16 octets
+	0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x00, 0x02, 0x76, 0x31, 0x23, 0xbd, 0x93, 0xcd,
+	0xf0, 0x9f, 0xa7, 0xae, 0x69, 0x73, 0x61, 0x30, 0x00, 0x00, 0x00, 0x0c, // isa0, 12 octets
+	0x01, 0x02, 0x03, 0x04, 0x0a, 0x0b, 0x0c, 0x0d, 0xfc, 0xef, 0x26, 0x84,
+	0xf0, 0x9f, 0x8f, 0xb7, 0x6b, 0x76, 0x73, 0x30, 0x00, 0x00, 0x00, 0x0e, // kvs0, 14 octets
+	0x00, 0x00, 0x00, 0x01, 0x00, 0x01, 0x6b, 0x00, 0x01, 0x76, 0x34, 0xc7, 0xdf, 0x38,
+}
+
+func fixedPackOptions() Options {
+	return Options{
+		ChunkChecksums: true,
+		InstructionSet: &InstructionSet{ID: 0x01020304, Version: 0x0a0b0c0d},
+		BuildMetadata:  &BuildMetadata{Timestamp: time.Unix(0, 0x0102030405060708).UTC(), ToolVersion: "v1"},
+		Metadata:       map[string]string{"k": "v"},
+	}
+}
+
+func TestFixedPackHeader(t *testing.T) {
+	chunkReader := newLimitedChunkReader(bytes.NewReader(fixedPack), Limits{})
+
+	version, flags, err := readPackHeaderChunk(chunkReader)
+	if err != nil {
+		t.Fatalf("read pack header: %v", err)
+	}
+
+	if version != (Version{Major: 5, Minor: 0}) {
+		t.Errorf("version is %v, expected 5.0", version)
+	}
+
+	if flags != 0x00000001 {
+		t.Errorf("flags are %08X, expected 00000001", flags)
+	}
+}
+
+func TestFixedPackChecksums(t *testing.T) {
+	cursor := NewCursor(fixedPack)
+
+	expected := []uint32{0x2ac9f220, 0x503a6e6c, 0x470b99f4, 0x23bd93cd, 0xfcef2684, 0x34c7df38}
+
+	index := 0
+	for chunk, ok := cursor.Next(); ok; chunk, ok = cursor.Next() {
+		if index >= len(expected) {
+			t.Fatalf("more chunks than the %d expected", len(expected))
+		}
+
+		stored := chunk.Payload[len(chunk.Payload)-chunkChecksumOctetCount:]
+		if got := binary.BigEndian.Uint32(stored); got != expected[index] {
+			t.Errorf("chunk %d stores checksum %08X, expected %08X", index, got, expected[index])
+		}
+
+		if got := crc32.ChecksumIEEE(chunk.Payload[:len(chunk.Payload)-chunkChecksumOctetCount]); got != expected[index] {
+			t.Errorf("chunk %d has checksum %08X, expected %08X", index, got, expected[index])
+		}
+
+		index++
+	}
+
+	if err := cursor.Err(); err != nil {
+		t.Fatalf("cursor: %v", err)
+	}
+
+	if index != len(expected) {
+		t.Errorf("found %d chunks, expected %d", index, len(expected))
+	}
+}
+
+func TestFixedPackContents(t *testing.T) {
+	contents := unpackData(t, fixedPack)
+	checkSampleContents(t, contents)
+
+	if contents.InstructionSet == nil || contents.InstructionSet.ID != 0x01020304 ||
+		contents.InstructionSet.Version != 0x0a0b0c0d {
+		t.Errorf("instruction set is %v, expected 16909060 v168496141", contents.InstructionSet)
+	}
+
+	if contents.BuildMetadata == nil || contents.BuildMetadata.Timestamp.UnixNano() != 72623859790382856 ||
+		contents.BuildMetadata.ToolVersion != "v1" {
+		t.Errorf("build metadata is %+v, expected 72623859790382856 v1", contents.BuildMetadata)
+	}
+
+	if !reflect.DeepEqual(contents.Metadata, map[string]string{"k": "v"}) {
+		t.Errorf("metadata is %v, expected map[k:v]", contents.Metadata)
+	}
+}
+
+func TestFixedPackEncoding(t *testing.T) {
+	if data := packSample(t, fixedPackOptions()); !bytes.Equal(data, fixedPack) {
+		t.Errorf("pack is\n% x\nexpected\n% x", data, fixedPack)
+	}
+}
+
+func TestFixedPackCorrupt(t *testing.T) {
+	corrupt := append([]byte{}, fixedPack...)
+	corrupt[len(corrupt)-1] ^= 0x01
+
+	if _, err := Unpack(bytes.NewReader(corrupt)); !errors.Is(err, ErrChunkChecksum) {
+		t.Fatalf("expected ErrChunkChecksum, got %v", err)
+	}
+}
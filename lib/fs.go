@@ -0,0 +1,24 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// UnpackFS opens and unpacks the pack called name in fsys. It works with any fs.FS, such as
+// embed.FS, os.DirFS or fstest.MapFS.
+func UnpackFS(fsys fs.FS, name string) (*Contents, error) {
+	file, openErr := fsys.Open(name)
+	if openErr != nil {
+		return nil, fmt.Errorf("unpack fs %w", openErr)
+	}
+
+	defer file.Close()
+
+	return Unpack(file)
+}
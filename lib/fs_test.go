@@ -0,0 +1,32 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestUnpackFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"packs/game.swamp-pack": &fstest.MapFile{Data: packSample(t, Options{ChunkChecksums: true})},
+	}
+
+	contents, err := UnpackFS(fsys, "packs/game.swamp-pack")
+	if err != nil {
+		t.Fatalf("unpack fs: %v", err)
+	}
+
+	checkSampleContents(t, contents)
+}
+
+func TestUnpackFSMissing(t *testing.T) {
+	if _, err := UnpackFS(fstest.MapFS{}, "missing.swamp-pack"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+const IconChunkIndex raff.FourOctets = 0xF09F9387 // 📇
+
+const chunkIndexEntryOctetCount = 4
+
+var chunkIndexName = raff.MakeFourOctets('i', 'd', 'x', '0')
+
+// chunkIndexPayload lists the names followed by their count, so the count is found at the end of the pack.
+func chunkIndexPayload(names []raff.FourOctets) []byte {
+	payload := make([]byte, chunkIndexEntryOctetCount*(len(names)+1))
+	for index, name := range names {
+		binary.BigEndian.PutUint32(payload[index*chunkIndexEntryOctetCount:], uint32(name))
+	}
+
+	binary.BigEndian.PutUint32(payload[len(names)*chunkIndexEntryOctetCount:], uint32(len(names)))
+
+	return payload
+}
+
+// chunkIndexOctetCount is the size of the idx0 chunk for chunkCount chunks, not counting the padding chunk.
+func (o Options) chunkIndexOctetCount(chunkCount int) int {
+	if !o.ChunkIndex {
+		return 0
+	}
+
+	if o.BlockAlign > 0 {
+		chunkCount++
+	}
+
+	return chunkHeaderOctetCount + chunkIndexEntryOctetCount*(chunkCount+1) + o.chunkTrailerOctetCount()
+}
+
+// readChunkIndex returns the count from an idx0 chunk at the very end of the pack. It returns false
+// if the last chunk is not an intact chunk index.
+func readChunkIndex(reader io.ReadSeeker, packHeaderEnd int64, flags uint32) (int, bool) {
+	end, endErr := reader.Seek(0, io.SeekEnd)
+	if endErr != nil {
+		return 0, false
+	}
+
+	trailerOctetCount := int64(0)
+	if flags&packFlagChunkChecksums != 0 {
+		trailerOctetCount = chunkChecksumOctetCount
+	}
+
+	countOffset := end - trailerOctetCount - chunkIndexEntryOctetCount
+	if countOffset-chunkHeaderOctetCount < packHeaderEnd {
+		return 0, false
+	}
+
+	if _, err := reader.Seek(countOffset, io.SeekStart); err != nil {
+		return 0, false
+	}
+
+	var count uint32
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return 0, false
+	}
+
+	start := countOffset - chunkIndexEntryOctetCount*int64(count) - chunkHeaderOctetCount
+	if start < packHeaderEnd {
+		return 0, false
+	}
+
+	if _, err := reader.Seek(start, io.SeekStart); err != nil {
+		return 0, false
+	}
+
+	header, headerErr := readFullChunkHeader(reader)
+	if headerErr != nil || header.Icon != IconChunkIndex || header.Name != chunkIndexName ||
+		int64(header.OctetCount) != end-start-chunkHeaderOctetCount ||
+		header.OctetCount > defaultPackLimits.MaxChunkOctetCount {
+		return 0, false
+	}
+
+	payload, payloadErr := readChunkPayload(reader, header)
+	if payloadErr != nil {
+		return 0, false
+	}
+
+	if flags&packFlagChunkChecksums != 0 {
+		if _, err := stripChunkChecksum(header, payload); err != nil {
+			return 0, false
+		}
+	}
+
+	return int(count), true
+}
+
+// NumChunks returns the number of chunks after the pack header, including dead chunks. It reads the
+// idx0 chunk written by the ChunkIndex option when it is the last chunk, and otherwise scans the chunk
+// headers. Chunk index chunks are never counted.
+func NumChunks(reader io.ReadSeeker) (int, error) {
+	if _, err := reader.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("num chunks seek %w", err)
+	}
+
+	chunkReader := newLimitedChunkReader(reader, Limits{})
+
+	flags, headerErr := readPackHeader(chunkReader)
+	if headerErr != nil {
+		return 0, headerErr
+	}
+
+	packHeaderEnd, offsetErr := reader.Seek(0, io.SeekCurrent)
+	if offsetErr != nil {
+		return 0, fmt.Errorf("num chunks seek %w", offsetErr)
+	}
+
+	if count, wasFound := readChunkIndex(reader, packHeaderEnd, flags); wasFound {
+		return count, nil
+	}
+
+	if _, err := reader.Seek(packHeaderEnd, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("num chunks seek %w", err)
+	}
+
+	count := 0
+
+	for {
+		header, readErr := chunkReader.readChunkHeader()
+		if errors.Is(readErr, io.EOF) {
+			return count, nil
+		}
+
+		if readErr != nil {
+			return 0, fmt.Errorf("num chunks read chunk %w", readErr)
+		}
+
+		if err := chunkReader.skipChunkPayload(header); err != nil {
+			return 0, err
+		}
+
+		if header.Name != chunkIndexName {
+			count++
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// scannedChunkCount counts the chunks after the pack header with a Cursor, leaving out chunk indices.
+func scannedChunkCount(t *testing.T, data []byte) int {
+	t.Helper()
+
+	cursor := NewCursor(data)
+
+	count := 0
+	for chunk, ok := cursor.Next(); ok; chunk, ok = cursor.Next() {
+		if chunk.Name != chunkIndexName {
+			count++
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		t.Fatalf("cursor: %v", err)
+	}
+
+	return count
+}
+
+func TestNumChunks(t *testing.T) {
+	for _, options := range []Options{
+		{},
+		{ChunkIndex: true},
+		{ChunkIndex: true, ChunkChecksums: true},
+		{ChunkIndex: true, BlockAlign: 512},
+		{ChunkIndex: true, BlockAlign: 64, ChunkChecksums: true, Translations: sampleTranslations()},
+	} {
+		data := packSample(t, options)
+
+		count, err := NumChunks(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("num chunks: %v", err)
+		}
+
+		if scanned := scannedChunkCount(t, data); count != scanned {
+			t.Errorf("NumChunks is %d, a scan found %d, with %+v", count, scanned, options)
+		}
+
+		checkSampleContents(t, unpackData(t, data))
+	}
+}
+
+func TestNumChunksUsesIndex(t *testing.T) {
+	data := packSample(t, Options{ChunkIndex: true})
+
+	// A scan would stop at the forged type info length, so only the index can give the count.
+	forged := append([]byte{}, data...)
+	binary.BigEndian.PutUint32(forged[typeInfoLengthOffset:], 0x7fffffff)
+
+	count, err := NumChunks(bytes.NewReader(forged))
+	if err != nil {
+		t.Fatalf("num chunks: %v", err)
+	}
+
+	if expected := scannedChunkCount(t, data); count != expected {
+		t.Errorf("NumChunks is %d, expected %d from the index", count, expected)
+	}
+
+	if !bytes.HasPrefix(data, packSample(t, Options{})) {
+		t.Error("the chunk index changed the chunks before it")
+	}
+}
+
+func TestNumChunksForgedIndex(t *testing.T) {
+	data := packSample(t, Options{ChunkIndex: true})
+
+	forged := append([]byte{}, data...)
+	forged[len(forged)-1] = 2
+
+	count, err := NumChunks(bytes.NewReader(forged))
+	if err != nil {
+		t.Fatalf("num chunks: %v", err)
+	}
+
+	if expected := scannedChunkCount(t, data); count != expected {
+		t.Errorf("NumChunks is %d for an index that does not fit, expected %d from a scan", count, expected)
+	}
+}
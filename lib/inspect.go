@@ -0,0 +1,105 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+type inspectedChunk struct {
+	Name       string `json:"name"`
+	Icon       string `json:"icon"`
+	OctetCount uint32 `json:"octetCount"`
+	Dead       bool   `json:"dead,omitempty"`
+}
+
+type inspection struct {
+	Version                  string           `json:"version"`
+	Chunks                   []inspectedChunk `json:"chunks"`
+	TypeInfoOctetCount       int              `json:"typeInfoOctetCount"`
+	ConstantMemoryOctetCount int              `json:"constantMemoryOctetCount"`
+	LedgerOctetCount         int              `json:"ledgerOctetCount"`
+	ChunkChecksums           bool             `json:"chunkChecksums"`
+	Compressed               bool             `json:"compressed"`
+	Signed                   bool             `json:"signed"`
+	BuildMetadata            bool             `json:"buildMetadata"`
+	SourceFiles              bool             `json:"sourceFiles"`
+	SourceHash               bool             `json:"sourceHash"`
+	InstructionSet           bool             `json:"instructionSet"`
+	Metadata                 bool             `json:"metadata"`
+	Translations             bool             `json:"translations"`
+	CustomChunkCount         int              `json:"customChunkCount"`
+}
+
+// InspectJSON checks the pack and describes it as a JSON document: the version, every chunk with its
+// size, the uncompressed payload sizes and which optional features are used. The payloads are opaque
+// to this package, so constants and functions are not counted.
+func InspectJSON(data []byte) ([]byte, error) {
+	contents, unpackErr := Unpack(bytes.NewReader(data))
+	if unpackErr != nil {
+		return nil, unpackErr
+	}
+
+	chunkReader := newLimitedChunkReader(bytes.NewReader(data), Limits{})
+
+	version, flags, headerErr := readPackHeaderChunk(chunkReader)
+	if headerErr != nil {
+		return nil, headerErr
+	}
+
+	report := inspection{
+		Version:                  version.String(),
+		Chunks:                   []inspectedChunk{},
+		TypeInfoOctetCount:       len(contents.TypeInfo),
+		ConstantMemoryOctetCount: len(contents.ConstantMemory),
+		LedgerOctetCount:         len(contents.Ledger),
+		ChunkChecksums:           flags&packFlagChunkChecksums != 0,
+		BuildMetadata:            contents.BuildMetadata != nil,
+		SourceFiles:              contents.SourceFiles != nil,
+		SourceHash:               contents.SourceHash != nil,
+		InstructionSet:           contents.InstructionSet != nil,
+		Metadata:                 contents.Metadata != nil,
+		Translations:             contents.Translations != nil,
+		CustomChunkCount:         len(contents.CustomChunks),
+	}
+
+	for {
+		header, readErr := chunkReader.readChunkHeader()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+
+		if readErr != nil {
+			return nil, fmt.Errorf("inspect read chunk %w", readErr)
+		}
+
+		if err := chunkReader.skipChunkPayload(header); err != nil {
+			return nil, err
+		}
+
+		isDead := header.Icon == IconDead
+		if !isDead {
+			_, isCompressed := compressedChunkNames[header.Name]
+			report.Compressed = report.Compressed || isCompressed
+			report.Signed = report.Signed || header.Name == signatureName
+		}
+
+		report.Chunks = append(report.Chunks, inspectedChunk{
+			Name:       raff.NameToString(header.Name),
+			Icon:       fmt.Sprintf("%08X", uint32(header.Icon)),
+			OctetCount: header.OctetCount,
+			Dead:       isDead,
+		})
+	}
+
+	return json.MarshalIndent(report, "", "  ")
+}
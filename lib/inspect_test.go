@@ -0,0 +1,77 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestInspectJSON(t *testing.T) {
+	sourceHash := sha256.Sum256([]byte("func main() {}"))
+	options := Options{
+		ChunkChecksums:    true,
+		LedgerCompression: CompressionZlib,
+		InstructionSet:    &InstructionSet{ID: 1, Version: 2},
+		SourceHash:        &sourceHash,
+		Metadata:          map[string]string{"commit": "3eb4de7"},
+	}
+
+	privateKey := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x42}, ed25519.SeedSize))
+
+	signed, signErr := Sign(packSample(t, options), privateKey)
+	if signErr != nil {
+		t.Fatalf("sign: %v", signErr)
+	}
+
+	document, err := InspectJSON(signed)
+	if err != nil {
+		t.Fatalf("inspect json: %v", err)
+	}
+
+	var report inspection
+	if err := json.Unmarshal(document, &report); err != nil {
+		t.Fatalf("decode json: %v", err)
+	}
+
+	if report.Version != "5.0" || report.TypeInfoOctetCount != len(sampleTypeInfo) ||
+		report.ConstantMemoryOctetCount != len(sampleConstantMemory) || report.LedgerOctetCount != len(sampleLedger) {
+		t.Errorf("unexpected version or sizes in %s", document)
+	}
+
+	if !report.ChunkChecksums || !report.Compressed || !report.Signed || !report.InstructionSet ||
+		!report.SourceHash || !report.Metadata {
+		t.Errorf("expected checksums, compression, a signature, isa0, srh0 and kvs0 in %s", document)
+	}
+
+	if report.BuildMetadata || report.SourceFiles || report.Translations || report.CustomChunkCount != 0 {
+		t.Errorf("unexpected optional chunks in %s", document)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(document, &fields); err != nil {
+		t.Fatalf("decode json: %v", err)
+	}
+
+	for _, key := range []string{"chunkChecksums", "compressed", "signed", "instructionSet", "sourceHash", "metadata"} {
+		if fields[key] != true {
+			t.Errorf("'%v' is %v, expected true", key, fields[key])
+		}
+	}
+
+	var names []string
+	for _, chunk := range report.Chunks {
+		names = append(names, chunk.Name)
+	}
+
+	if expected := []string{"sti0", "dme1", "ldz0", "isa0", "srh0", "kvs0", "sig0"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("chunks are %q, expected %q", names, expected)
+	}
+}
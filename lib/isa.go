@@ -0,0 +1,66 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+const IconInstructionSet raff.FourOctets = 0xF09FA7AE // 🧮
+
+const instructionSetOctetCount = 8
+
+var (
+	ErrInstructionSetMismatch = errors.New("instruction set mismatch")
+	instructionSetName        = raff.MakeFourOctets('i', 's', 'a', '0')
+)
+
+// InstructionSet identifies the opcode format of the ledger, so a loader can reject opcodes it can not execute.
+type InstructionSet struct {
+	ID      uint32
+	Version uint32
+}
+
+func (s InstructionSet) String() string {
+	return fmt.Sprintf("%d v%d", s.ID, s.Version)
+}
+
+func instructionSetPayload(set InstructionSet) []byte {
+	payload := make([]byte, instructionSetOctetCount)
+	binary.BigEndian.PutUint32(payload[0:4], set.ID)
+	binary.BigEndian.PutUint32(payload[4:8], set.Version)
+
+	return payload
+}
+
+func readInstructionSet(payload []byte) (*InstructionSet, error) {
+	if len(payload) != instructionSetOctetCount {
+		return nil, fmt.Errorf("instruction set chunk has %d octets, expected %d", len(payload), instructionSetOctetCount)
+	}
+
+	return &InstructionSet{
+		ID:      binary.BigEndian.Uint32(payload[0:4]),
+		Version: binary.BigEndian.Uint32(payload[4:8]),
+	}, nil
+}
+
+// RequireInstructionSet returns ErrInstructionSetMismatch unless the contents were packed for the instruction set want.
+// Contents without an isa0 chunk do not match any instruction set.
+func RequireInstructionSet(contents *Contents, want InstructionSet) error {
+	if contents.InstructionSet == nil {
+		return fmt.Errorf("%w: pack has no instruction set, expected %v", ErrInstructionSetMismatch, want)
+	}
+
+	if got := *contents.InstructionSet; got != want {
+		return fmt.Errorf("%w: pack has %v, expected %v", ErrInstructionSetMismatch, got, want)
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInstructionSetRoundTrip(t *testing.T) {
+	set := InstructionSet{ID: 0x53574d50, Version: 3}
+
+	contents := unpackData(t, packSample(t, Options{InstructionSet: &set}))
+	checkSampleContents(t, contents)
+
+	if contents.InstructionSet == nil || *contents.InstructionSet != set {
+		t.Fatalf("instruction set is %v, expected %v", contents.InstructionSet, set)
+	}
+
+	if err := RequireInstructionSet(contents, set); err != nil {
+		t.Errorf("require instruction set: %v", err)
+	}
+}
+
+func TestInstructionSetMismatch(t *testing.T) {
+	set := InstructionSet{ID: 0x53574d50, Version: 3}
+	contents := unpackData(t, packSample(t, Options{InstructionSet: &set}))
+
+	for _, want := range []InstructionSet{{ID: set.ID, Version: 4}, {ID: 1, Version: set.Version}} {
+		if err := RequireInstructionSet(contents, want); !errors.Is(err, ErrInstructionSetMismatch) {
+			t.Errorf("expected ErrInstructionSetMismatch for %v, got %v", want, err)
+		}
+	}
+
+	if err := RequireInstructionSet(unpackData(t, packSample(t, Options{})), set); !errors.Is(err, ErrInstructionSetMismatch) {
+		t.Errorf("expected ErrInstructionSetMismatch for a pack without an instruction set, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+var (
+	ErrChunkTooLarge  = errors.New("chunk too large")
+	ErrPackTooLarge   = errors.New("pack too large")
+	ErrTooManyChunks  = errors.New("too many chunks")
+	defaultPackLimits = Limits{
+		MaxChunkOctetCount: 256 * 1024 * 1024,
+		MaxTotalOctetCount: 1024 * 1024 * 1024,
+		MaxChunkCount:      1024,
+	}
+)
+
+// Limits bounds what a reader accepts before allocating. Fields left at zero use DefaultLimits.
+type Limits struct {
+	MaxChunkOctetCount uint32
+	MaxTotalOctetCount int64
+	MaxChunkCount      int
+}
+
+func DefaultLimits() Limits {
+	return defaultPackLimits
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxChunkOctetCount == 0 {
+		l.MaxChunkOctetCount = defaultPackLimits.MaxChunkOctetCount
+	}
+
+	if l.MaxTotalOctetCount == 0 {
+		l.MaxTotalOctetCount = defaultPackLimits.MaxTotalOctetCount
+	}
+
+	if l.MaxChunkCount == 0 {
+		l.MaxChunkCount = defaultPackLimits.MaxChunkCount
+	}
+
+	return l
+}
+
+type limitedChunkReader struct {
+	reader          io.Reader
+	limits          Limits
+	chunkCount      int
+	totalOctetCount int64
+	flags           uint32
+}
+
+func newLimitedChunkReader(reader io.Reader, limits Limits) *limitedChunkReader {
+	return &limitedChunkReader{
+		reader:          reader,
+		limits:          limits.withDefaults(),
+		totalOctetCount: int64(len(raff.FileHeader())),
+	}
+}
+
+// readFullChunkHeader reads all octets of a chunk header at once. raff.ReadChunkHeader reads it field by
+// field and returns a bare io.EOF when the data ends between fields, which would look like the end of the
+// pack. Here io.EOF means that the reader was already at its end, and a partial header is io.ErrUnexpectedEOF.
+func readFullChunkHeader(reader io.Reader) (raff.ChunkHeader, error) {
+	var octets [chunkHeaderOctetCount]byte
+	if _, err := io.ReadFull(reader, octets[:]); err != nil {
+		return raff.ChunkHeader{}, err
+	}
+
+	return raff.ChunkHeader{
+		ChunkMarkerHeader: raff.ChunkMarkerHeader{
+			Icon: raff.FourOctets(binary.BigEndian.Uint32(octets[0:4])),
+			Name: raff.FourOctets(binary.BigEndian.Uint32(octets[4:8])),
+		},
+		OctetCount: binary.BigEndian.Uint32(octets[8:12]),
+	}, nil
+}
+
+func (r *limitedChunkReader) readChunkHeader() (raff.ChunkHeader, error) {
+	header, headerErr := readFullChunkHeader(r.reader)
+	if headerErr != nil {
+		return raff.ChunkHeader{}, headerErr
+	}
+
+	r.chunkCount++
+	if r.chunkCount > r.limits.MaxChunkCount {
+		return raff.ChunkHeader{}, fmt.Errorf("%w: more than %d", ErrTooManyChunks, r.limits.MaxChunkCount)
+	}
+
+	if header.OctetCount > r.limits.MaxChunkOctetCount {
+		return raff.ChunkHeader{}, fmt.Errorf("%w: '%v' declares %d octets (max %d)", ErrChunkTooLarge,
+			raff.NameToString(header.Name), header.OctetCount, r.limits.MaxChunkOctetCount)
+	}
+
+	r.totalOctetCount += chunkHeaderOctetCount + int64(header.OctetCount)
+	if r.totalOctetCount > r.limits.MaxTotalOctetCount {
+		return raff.ChunkHeader{}, fmt.Errorf("%w: more than %d octets", ErrPackTooLarge, r.limits.MaxTotalOctetCount)
+	}
+
+	return header, nil
+}
+
+func (r *limitedChunkReader) readChunkPayload(header raff.ChunkHeader) ([]byte, error) {
+	if source, isInMemory := r.reader.(*sliceReader); isInMemory {
+		return source.readChunkPayload(header)
+	}
+
+	return readChunkPayload(r.reader, header)
+}
+
+func (r *limitedChunkReader) readChunk() (raff.ChunkHeader, []byte, error) {
+	header, headerErr := r.readChunkHeader()
+	if headerErr != nil {
+		return raff.ChunkHeader{}, nil, headerErr
+	}
+
+	payload, payloadErr := r.readChunkPayload(header)
+	if payloadErr != nil {
+		return raff.ChunkHeader{}, nil, payloadErr
+	}
+
+	return header, payload, nil
+}
+
+func (r *limitedChunkReader) skipChunkPayload(header raff.ChunkHeader) error {
+	if _, err := io.CopyN(io.Discard, r.reader, int64(header.OctetCount)); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return fmt.Errorf("chunk '%v' %w", raff.NameToString(header.Name), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"runtime"
+	"testing"
+)
+
+// typeInfoLengthOffset is where the octet count of sti0 is in a pack packed without options.
+const typeInfoLengthOffset = 9 + chunkHeaderOctetCount + 8
+
+func forgedTypeInfoLength(t *testing.T, octetCount uint32) []byte {
+	t.Helper()
+
+	data := packSample(t, Options{})
+	binary.BigEndian.PutUint32(data[typeInfoLengthOffset:], octetCount)
+
+	return data
+}
+
+func TestUnpackAbsurdChunkLength(t *testing.T) {
+	data := forgedTypeInfoLength(t, 0xfffffff0)
+
+	if _, err := Unpack(bytes.NewReader(data)); !errors.Is(err, ErrChunkTooLarge) {
+		t.Errorf("Unpack returned %v, expected ErrChunkTooLarge", err)
+	}
+
+	if err := Verify(bytes.NewReader(data)); !errors.Is(err, ErrChunkTooLarge) {
+		t.Errorf("Verify returned %v, expected ErrChunkTooLarge", err)
+	}
+
+	if err := VerifyStream(bytes.NewReader(data), Limits{}); !errors.Is(err, ErrChunkTooLarge) {
+		t.Errorf("VerifyStream returned %v, expected ErrChunkTooLarge", err)
+	}
+}
+
+func TestUnpackLimits(t *testing.T) {
+	data := packSample(t, Options{})
+
+	for _, limits := range []Limits{
+		{MaxChunkOctetCount: 4},
+		{MaxTotalOctetCount: int64(len(data) - 1)},
+		{MaxChunkCount: 3},
+	} {
+		if _, err := UnpackWithOptions(bytes.NewReader(data), UnpackOptions{Limits: limits}); err == nil {
+			t.Errorf("limits %+v accepted a pack of %d octets", limits, len(data))
+		}
+	}
+
+	if _, err := UnpackWithOptions(bytes.NewReader(data), UnpackOptions{Limits: DefaultLimits()}); err != nil {
+		t.Errorf("default limits rejected the pack: %v", err)
+	}
+}
+
+// TestForgedLengthDoesNotAllocate checks that no reader trusts a declared chunk length before
+// checking it against the limits or the data it has.
+func TestForgedLengthDoesNotAllocate(t *testing.T) {
+	data := forgedTypeInfoLength(t, 0x0fffffff)
+	publicKey, _, _ := ed25519.GenerateKey(nil)
+
+	readers := map[string]func() error{
+		"Compact": func() error {
+			_, err := Compact(data)
+			return err
+		},
+		"Strip": func() error {
+			_, err := Strip(data)
+			return err
+		},
+		"StripTranslations": func() error {
+			_, err := StripTranslations(data)
+			return err
+		},
+		"Canonicalize": func() error {
+			_, err := Canonicalize(data)
+			return err
+		},
+		"UnpackWithPublicKey": func() error {
+			_, err := UnpackWithOptions(bytes.NewReader(data), UnpackOptions{PublicKey: publicKey,
+				Limits: Limits{MaxChunkOctetCount: 1024}})
+			return err
+		},
+		"NumChunks": func() error {
+			_, err := NumChunks(bytes.NewReader(data))
+			return err
+		},
+		"OpenArchive": func() error {
+			_, err := OpenArchive(bytes.NewReader(data))
+			return err
+		},
+	}
+
+	for name, read := range readers {
+		var before, after runtime.MemStats
+
+		runtime.ReadMemStats(&before)
+		err := read()
+		runtime.ReadMemStats(&after)
+
+		if err == nil {
+			t.Errorf("%v accepted a forged chunk length", name)
+		}
+
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1024*1024 {
+			t.Errorf("%v allocated %d octets for a forged chunk length", name, allocated)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+const IconMetadata raff.FourOctets = 0xF09F8FB7 // 🏷
+
+var metadataName = raff.MakeFourOctets('k', 'v', 's', '0')
+
+// metadataPayload writes the pairs sorted by key, so the same map always gives the same octets.
+func metadataPayload(metadata map[string]string) ([]byte, error) {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var payload bytes.Buffer
+
+	binary.Write(&payload, binary.BigEndian, uint32(len(keys)))
+	for _, key := range keys {
+		if err := writeString(&payload, key); err != nil {
+			return nil, fmt.Errorf("metadata key %w", err)
+		}
+
+		if err := writeString(&payload, metadata[key]); err != nil {
+			return nil, fmt.Errorf("metadata value for '%v' %w", key, err)
+		}
+	}
+
+	return payload.Bytes(), nil
+}
+
+func readMetadata(payload []byte) (map[string]string, error) {
+	reader := bytes.NewReader(payload)
+
+	var count uint32
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, fmt.Errorf("metadata count %w", err)
+	}
+
+	if int64(count)*4 > int64(reader.Len()) {
+		return nil, fmt.Errorf("metadata count %d does not fit in chunk", count)
+	}
+
+	metadata := make(map[string]string, count)
+	for index := uint32(0); index < count; index++ {
+		key, keyErr := readString(reader)
+		if keyErr != nil {
+			return nil, fmt.Errorf("metadata key %d %w", index, keyErr)
+		}
+
+		value, valueErr := readString(reader)
+		if valueErr != nil {
+			return nil, fmt.Errorf("metadata value for '%v' %w", key, valueErr)
+		}
+
+		metadata[key] = value
+	}
+
+	return metadata, nil
+}
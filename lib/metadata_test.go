@@ -0,0 +1,40 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMetadataRoundTrip(t *testing.T) {
+	metadata := map[string]string{"commit": "3eb4de7", "features": "netcode,replay", "empty": ""}
+
+	contents := unpackData(t, packSample(t, Options{Metadata: metadata}))
+	checkSampleContents(t, contents)
+
+	if !reflect.DeepEqual(contents.Metadata, metadata) {
+		t.Errorf("metadata is %v, expected %v", contents.Metadata, metadata)
+	}
+}
+
+func TestMetadataIsDeterministic(t *testing.T) {
+	metadata := map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"}
+
+	first := packSample(t, Options{Metadata: metadata})
+	for i := 0; i < 8; i++ {
+		if !bytes.Equal(packSample(t, Options{Metadata: metadata}), first) {
+			t.Fatal("packing the same metadata gave different octets")
+		}
+	}
+}
+
+func TestMetadataIsOptional(t *testing.T) {
+	if contents := unpackData(t, packSample(t, Options{})); contents.Metadata != nil {
+		t.Errorf("pack without metadata unpacked metadata %v", contents.Metadata)
+	}
+}
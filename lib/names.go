@@ -0,0 +1,58 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"errors"
+	"fmt"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+var (
+	ErrInvalidChunkName  = errors.New("invalid chunk name")
+	ErrReservedChunkName = errors.New("reserved chunk name")
+)
+
+// reservedChunkNames are the chunks written by this package. Any name sharing their first three
+// octets is reserved, so custom chunks can not shadow other versions of them either.
+var reservedChunkNames = []raff.FourOctets{
+	packHeaderName(CurrentVersion.Major),
+	typeInfoName,
+	constantMemoryName,
+	ledgerName,
+	compressedConstantMemoryName,
+	compressedLedgerName,
+	paddingName,
+	buildMetadataName,
+	instructionSetName,
+	sourceFilesName,
+	sourceHashName,
+	metadataName,
+	translationName,
+	externalConstantMemoryName,
+	chunkIndexName,
+	signatureName,
+}
+
+// ValidChunkName checks that a custom chunk name is four printable ASCII octets and does not
+// collide with a chunk name reserved by this package.
+func ValidChunkName(name raff.FourOctets) error {
+	for shift := 24; shift >= 0; shift -= 8 {
+		octet := byte(name >> shift)
+		if octet < 0x21 || octet > 0x7e {
+			return fmt.Errorf("%w: %08X", ErrInvalidChunkName, uint32(name))
+		}
+	}
+
+	for _, reserved := range reservedChunkNames {
+		if name&0xffffff00 == reserved&0xffffff00 {
+			return fmt.Errorf("%w: '%v'", ErrReservedChunkName, raff.NameToString(name))
+		}
+	}
+
+	return nil
+}
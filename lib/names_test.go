@@ -0,0 +1,65 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+func TestValidChunkName(t *testing.T) {
+	for _, name := range []raff.FourOctets{
+		raff.MakeFourOctets('g', 'f', 'x', '0'),
+		raff.MakeFourOctets('A', '~', '!', '9'),
+	} {
+		if err := ValidChunkName(name); err != nil {
+			t.Errorf("'%v': %v", raff.NameToString(name), err)
+		}
+	}
+
+	for _, name := range []raff.FourOctets{
+		raff.MakeFourOctets('g', 'f', 'x', ' '),
+		raff.MakeFourOctets(0x00, 'f', 'x', '0'),
+		raff.MakeFourOctets('g', 0x7f, 'x', '0'),
+		raff.MakeFourOctets('g', 'f', 0xf0, '0'),
+	} {
+		if err := ValidChunkName(name); !errors.Is(err, ErrInvalidChunkName) {
+			t.Errorf("%08X: expected ErrInvalidChunkName, got %v", uint32(name), err)
+		}
+	}
+
+	for _, name := range []raff.FourOctets{
+		ledgerName,
+		raff.MakeFourOctets('l', 'd', 'g', '7'),
+		raff.MakeFourOctets('s', 'p', 'k', '9'),
+		raff.MakeFourOctets('s', 'i', 'g', '1'),
+	} {
+		if err := ValidChunkName(name); !errors.Is(err, ErrReservedChunkName) {
+			t.Errorf("'%v': expected ErrReservedChunkName, got %v", raff.NameToString(name), err)
+		}
+	}
+}
+
+func TestCustomChunks(t *testing.T) {
+	custom := Chunk{Icon: 0xF09F8EA8, Name: raff.MakeFourOctets('g', 'f', 'x', '0'), Payload: []byte{0x01, 0x02}}
+
+	contents := unpackData(t, packSample(t, Options{CustomChunks: []Chunk{custom}}))
+	checkSampleContents(t, contents)
+
+	if len(contents.CustomChunks) != 1 || contents.CustomChunks[0].Name != custom.Name ||
+		!bytes.Equal(contents.CustomChunks[0].Payload, custom.Payload) {
+		t.Errorf("custom chunks are %v, expected %v", contents.CustomChunks, custom)
+	}
+
+	reserved := Chunk{Icon: IconLedger, Name: raff.MakeFourOctets('l', 'd', 'g', '1'), Payload: []byte{0x01}}
+	if _, err := PackWithOptions(sampleLedger, sampleConstantMemory, sampleTypeInfo,
+		Options{CustomChunks: []Chunk{reserved}}); !errors.Is(err, ErrReservedChunkName) {
+		t.Errorf("expected ErrReservedChunkName for a custom chunk, got %v", err)
+	}
+}
@@ -10,63 +10,384 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 
 	raff "github.com/piot/raff-go/src"
 )
 
+// Chunk icons are the UTF-8 encoded emojis that precede each chunk name.
+const (
+	IconPack           raff.FourOctets = 0xF09F93A6 // 📦
+	IconTypeInfo       raff.FourOctets = 0xF09F939C // 📜
+	IconConstantMemory raff.FourOctets = 0xF09F92BB // 💻
+	IconLedger         raff.FourOctets = 0xF09F9792 // 🗒
+	IconPadding        raff.FourOctets = 0xF09FA7B1 // 🧱
+)
+
+var (
+	typeInfoName       = raff.MakeFourOctets('s', 't', 'i', '0')
+	constantMemoryName = raff.MakeFourOctets('d', 'm', 'e', '1')
+	ledgerName         = raff.MakeFourOctets('l', 'd', 'g', '0')
+	paddingName        = raff.MakeFourOctets('p', 'a', 'd', '0')
+)
+
+const chunkHeaderOctetCount = 12
+
+// packFlagChunkChecksums is stored in the pack header. Flags added by a later minor version
+// must be safe for older readers to ignore, since those readers accept newer minor versions.
+const packFlagChunkChecksums uint32 = 1 << 0
+
+// Options changes how PackWithOptions writes a pack. The zero value writes the same pack as Pack.
+type Options struct {
+	// BlockAlign pads the pack to a multiple of BlockAlign octets using a padding chunk. Zero means no padding.
+	BlockAlign int
+
+	// BuildMetadata is written to a bld0 chunk when set. It is omitted by default to keep builds reproducible.
+	BuildMetadata *BuildMetadata
+
+	// InstructionSet is written to an isa0 chunk when set, so loaders can check the opcode format of the ledger.
+	InstructionSet *InstructionSet
+
+	// SourceFiles is written to a src0 chunk when not empty. Debug information refers to a file by its index.
+	SourceFiles []string
+
+	// SourceHash is written to an srh0 chunk when set, so a build can be traced to the SHA-256 of its source.
+	SourceHash *[32]byte
+
+	// Metadata is written to a kvs0 chunk when not empty, for free-form annotations such as a commit id.
+	Metadata map[string]string
+
+	// Translations are written as one i18n chunk per language, each mapping a message id to its text.
+	Translations map[string]map[string]string
+
+	// ChunkChecksums appends a CRC-32 of the payload to every chunk after the pack header, so
+	// Unpack can tell which chunk is corrupt. It is recorded as a flag in the pack header chunk.
+	ChunkChecksums bool
+
+	// ChunkIndex writes an idx0 chunk with the names of all chunks as the last chunk, so NumChunks
+	// can answer by reading the end of the pack.
+	ChunkIndex bool
+
+	// CustomChunks are written after the standard chunks. Their names must pass ValidChunkName.
+	CustomChunks []Chunk
+
+	// LedgerCompression and ConstantMemoryCompression compress the ledger and the constant memory
+	// separately, as ldz0 and dmz1 chunks, since opcodes and constants compress differently.
+	LedgerCompression         Compression
+	ConstantMemoryCompression Compression
+
+	// BufferPool provides the buffers used while packing, including the returned pack. Nil allocates normally.
+	BufferPool BufferPool
+}
+
+// BufferPool lets callers control where pack buffers come from, e.g. backed by a sync.Pool or an arena.
+// Get must return a slice with a capacity of at least n. The pack returned by PackWithOptions is taken
+// from Get and can be handed back with Put once the caller is done with it.
+type BufferPool interface {
+	Get(n int) []byte
+	Put([]byte)
+}
+
+// Chunk is a single RAFF chunk of a pack.
+type Chunk struct {
+	Icon    raff.FourOctets
+	Name    raff.FourOctets
+	Payload []byte
+}
+
+// ChunkWriteError is returned when writing a chunk fails, e.g. on a flaky network connection.
+// Written is the number of octets of the chunk, including its header, that were written before the failure.
+type ChunkWriteError struct {
+	Name    raff.FourOctets
+	Written int64
+	Err     error
+}
+
+func (e *ChunkWriteError) Error() string {
+	return fmt.Sprintf("write chunk '%v' failed after %d octets: %v", raff.NameToString(e.Name), e.Written, e.Err)
+}
+
+func (e *ChunkWriteError) Unwrap() error {
+	return e.Err
+}
+
+type countingWriter struct {
+	writer     io.Writer
+	octetCount int64
+}
+
+func (w *countingWriter) Write(octets []byte) (int, error) {
+	octetCount, err := w.writer.Write(octets)
+	w.octetCount += int64(octetCount)
+
+	return octetCount, err
+}
+
 func writeChunkHeader(writer io.Writer, icon raff.FourOctets, name raff.FourOctets, payload []byte) error {
-	if err := raff.WriteChunk(writer, icon, name, payload); err != nil {
-		return err
+	if uint64(len(payload)) > math.MaxUint32 {
+		return fmt.Errorf("%w: '%v' has %d octets, the chunk length is 32-bit", ErrChunkTooLarge,
+			raff.NameToString(name), len(payload))
+	}
+
+	counter := &countingWriter{writer: writer}
+	if err := raff.WriteChunk(counter, icon, name, payload); err != nil {
+		return &ChunkWriteError{Name: name, Written: counter.octetCount, Err: err}
 	}
 
 	return nil
 }
 
-func writePackHeader(writer io.Writer) error {
-	name := raff.MakeFourOctets('s', 'p', 'k', '5')
-	packetIcon := raff.MakeFourOctets(0xF0, 0x9F, 0x93, 0xA6)
-	return writeChunkHeader(writer, packetIcon, name, nil)
+func writePackHeader(writer io.Writer, flags uint32) error {
+	return writeChunkHeader(writer, IconPack, packHeaderName(CurrentVersion.Major), packHeaderPayload(CurrentVersion, flags))
 }
 
 func writeTypeInfo(writer io.Writer, payload []byte) error {
-	name := raff.MakeFourOctets('s', 't', 'i', '0')
-	packetIcon := raff.MakeFourOctets(0xF0, 0x9F, 0x93, 0x9C)
-	return writeChunkHeader(writer, packetIcon, name, payload)
+	return writeChunkHeader(writer, IconTypeInfo, typeInfoName, payload)
 }
 
 func writeConstantMemory(writer io.Writer, payload []byte) error {
-	name := raff.MakeFourOctets('d', 'm', 'e', '1')
-	packetIcon := raff.MakeFourOctets(0xF0, 0x9F, 0x92, 0xBB)
-	return writeChunkHeader(writer, packetIcon, name, payload)
+	return writeChunkHeader(writer, IconConstantMemory, constantMemoryName, payload)
 }
 
 func writeLedger(writer io.Writer, payload []byte) error {
-	name := raff.MakeFourOctets('l', 'd', 'g', '0')
-	packetIcon := raff.MakeFourOctets(0xF0, 0x9F, 0x97, 0x92)
-	return writeChunkHeader(writer, packetIcon, name, payload)
+	return writeChunkHeader(writer, IconLedger, ledgerName, payload)
+}
+
+func paddingPayloadOctetCount(packOctetCount int, blockAlign int) int {
+	remainder := (packOctetCount + chunkHeaderOctetCount) % blockAlign
+	if remainder == 0 {
+		return 0
+	}
+
+	return blockAlign - remainder
+}
+
+func (o Options) flags() uint32 {
+	var flags uint32
+	if o.ChunkChecksums {
+		flags |= packFlagChunkChecksums
+	}
+
+	return flags
+}
+
+func (o Options) getBuffer(octetCount int) []byte {
+	if o.BufferPool == nil {
+		return make([]byte, octetCount)
+	}
+
+	return o.BufferPool.Get(octetCount)[:octetCount]
+}
+
+func (o Options) putBuffer(buffer []byte) {
+	if o.BufferPool != nil {
+		o.BufferPool.Put(buffer)
+	}
+}
+
+func (o Options) writeChunk(writer io.Writer, icon raff.FourOctets, name raff.FourOctets, payload []byte) error {
+	if !o.ChunkChecksums {
+		return writeChunkHeader(writer, icon, name, payload)
+	}
+
+	sealed := o.getBuffer(len(payload) + chunkChecksumOctetCount)
+	sealChunkPayload(sealed, payload)
+	writeErr := writeChunkHeader(writer, icon, name, sealed)
+	o.putBuffer(sealed)
+
+	return writeErr
+}
+
+func (o Options) chunkTrailerOctetCount() int {
+	if o.ChunkChecksums {
+		return chunkChecksumOctetCount
+	}
+
+	return 0
+}
+
+func optionalChunks(options Options) ([]Chunk, error) {
+	var chunks []Chunk
+
+	if options.BuildMetadata != nil {
+		payload, payloadErr := buildMetadataPayload(options.BuildMetadata)
+		if payloadErr != nil {
+			return nil, payloadErr
+		}
+
+		chunks = append(chunks, Chunk{Icon: IconBuildMetadata, Name: buildMetadataName, Payload: payload})
+	}
+
+	if options.InstructionSet != nil {
+		chunks = append(chunks, Chunk{Icon: IconInstructionSet, Name: instructionSetName,
+			Payload: instructionSetPayload(*options.InstructionSet)})
+	}
+
+	if len(options.SourceFiles) > 0 {
+		payload, payloadErr := sourceFilesPayload(options.SourceFiles)
+		if payloadErr != nil {
+			return nil, payloadErr
+		}
+
+		chunks = append(chunks, Chunk{Icon: IconSourceFiles, Name: sourceFilesName, Payload: payload})
+	}
+
+	if options.SourceHash != nil {
+		chunks = append(chunks, Chunk{Icon: IconSourceHash, Name: sourceHashName, Payload: options.SourceHash[:]})
+	}
+
+	if len(options.Metadata) > 0 {
+		payload, payloadErr := metadataPayload(options.Metadata)
+		if payloadErr != nil {
+			return nil, payloadErr
+		}
+
+		chunks = append(chunks, Chunk{Icon: IconMetadata, Name: metadataName, Payload: payload})
+	}
+
+	if len(options.Translations) > 0 {
+		translations, translationsErr := translationChunks(options.Translations)
+		if translationsErr != nil {
+			return nil, translationsErr
+		}
+
+		chunks = append(chunks, translations...)
+	}
+
+	for _, custom := range options.CustomChunks {
+		if err := ValidChunkName(custom.Name); err != nil {
+			return nil, err
+		}
+
+		if custom.Icon == IconDead {
+			return nil, fmt.Errorf("custom chunk '%v' can not use the dead icon", raff.NameToString(custom.Name))
+		}
+
+		chunks = append(chunks, custom)
+	}
+
+	return chunks, nil
+}
+
+func packChunks(ledger []byte, constantMemory []byte, typeInfo []byte, options Options) ([]Chunk, error) {
+	constantMemoryChunk, constantMemoryErr := compressChunk(
+		Chunk{Icon: IconConstantMemory, Name: constantMemoryName, Payload: constantMemory},
+		compressedConstantMemoryName, options.ConstantMemoryCompression)
+	if constantMemoryErr != nil {
+		return nil, constantMemoryErr
+	}
+
+	ledgerChunk, ledgerErr := compressChunk(Chunk{Icon: IconLedger, Name: ledgerName, Payload: ledger},
+		compressedLedgerName, options.LedgerCompression)
+	if ledgerErr != nil {
+		return nil, ledgerErr
+	}
+
+	chunks := []Chunk{
+		{Icon: IconTypeInfo, Name: typeInfoName, Payload: typeInfo},
+		constantMemoryChunk,
+		ledgerChunk,
+	}
+
+	extraChunks, extraErr := optionalChunks(options)
+	if extraErr != nil {
+		return nil, extraErr
+	}
+
+	return append(chunks, extraChunks...), nil
+}
+
+func packedOctetCount(chunks []Chunk, options Options) int {
+	octetCount := len(raff.FileHeader()) + chunkHeaderOctetCount + len(packHeaderPayload(CurrentVersion, options.flags()))
+	for _, chunk := range chunks {
+		octetCount += chunkHeaderOctetCount + len(chunk.Payload) + options.chunkTrailerOctetCount()
+	}
+
+	indexOctetCount := options.chunkIndexOctetCount(len(chunks))
+
+	if options.BlockAlign > 0 {
+		octetCount += options.chunkTrailerOctetCount()
+		octetCount += chunkHeaderOctetCount + paddingPayloadOctetCount(octetCount+indexOctetCount, options.BlockAlign)
+	}
+
+	return octetCount + indexOctetCount
+}
+
+// EstimatedSize returns the exact number of octets PackWithOptions produces for the same arguments,
+// or the error PackWithOptions would return.
+func EstimatedSize(ledger []byte, constantMemory []byte, typeInfo []byte, options Options) (int, error) {
+	if options.BlockAlign < 0 {
+		return 0, fmt.Errorf("pack block align must not be negative (%d)", options.BlockAlign)
+	}
+
+	chunks, chunksErr := packChunks(ledger, constantMemory, typeInfo, options)
+	if chunksErr != nil {
+		return 0, chunksErr
+	}
+
+	return packedOctetCount(chunks, options), nil
 }
 
 func Pack(ledger []byte, constantMemory []byte, typeInfo []byte) ([]byte, error) {
-	var buf bytes.Buffer
+	return PackWithOptions(ledger, constantMemory, typeInfo, Options{})
+}
+
+func PackWithOptions(ledger []byte, constantMemory []byte, typeInfo []byte, options Options) ([]byte, error) {
+	if options.BlockAlign < 0 {
+		return nil, fmt.Errorf("pack block align must not be negative (%d)", options.BlockAlign)
+	}
 
-	if err := raff.WriteHeader(&buf); err != nil {
+	chunks, chunksErr := packChunks(ledger, constantMemory, typeInfo, options)
+	if chunksErr != nil {
+		return nil, chunksErr
+	}
+
+	buf := bytes.NewBuffer(options.getBuffer(packedOctetCount(chunks, options))[:0])
+
+	if err := raff.WriteHeader(buf); err != nil {
 		return nil, fmt.Errorf("pack write header %w", err)
 	}
 
-	if writeErr := writePackHeader(&buf); writeErr != nil {
+	if writeErr := writePackHeader(buf, options.flags()); writeErr != nil {
 		return nil, writeErr
 	}
 
-	if writeErr := writeTypeInfo(&buf, typeInfo); writeErr != nil {
-		return nil, writeErr
+	for _, chunk := range chunks {
+		if writeErr := options.writeChunk(buf, chunk.Icon, chunk.Name, chunk.Payload); writeErr != nil {
+			return nil, writeErr
+		}
 	}
 
-	if writeErr := writeConstantMemory(&buf, constantMemory); writeErr != nil {
-		return nil, writeErr
+	indexOctetCount := options.chunkIndexOctetCount(len(chunks))
+
+	if options.BlockAlign > 0 {
+		padding := options.getBuffer(paddingPayloadOctetCount(buf.Len()+options.chunkTrailerOctetCount()+indexOctetCount,
+			options.BlockAlign))
+		for index := range padding {
+			padding[index] = 0
+		}
+
+		writeErr := options.writeChunk(buf, IconPadding, paddingName, padding)
+		options.putBuffer(padding)
+
+		if writeErr != nil {
+			return nil, writeErr
+		}
 	}
 
-	if writeErr := writeLedger(&buf, ledger); writeErr != nil {
-		return nil, writeErr
+	if options.ChunkIndex {
+		names := make([]raff.FourOctets, 0, len(chunks)+1)
+		for _, chunk := range chunks {
+			names = append(names, chunk.Name)
+		}
+
+		if options.BlockAlign > 0 {
+			names = append(names, paddingName)
+		}
+
+		if writeErr := options.writeChunk(buf, IconChunkIndex, chunkIndexName, chunkIndexPayload(names)); writeErr != nil {
+			return nil, writeErr
+		}
 	}
 
 	return buf.Bytes(), nil
@@ -0,0 +1,268 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+var (
+	sampleLedger         = []byte{0x01, 0x02, 0x03, 0x04, 0x05}
+	sampleConstantMemory = []byte{0x10, 0x20, 0x30}
+	sampleTypeInfo       = []byte{0xca, 0xfe}
+)
+
+func packSample(t *testing.T, options Options) []byte {
+	t.Helper()
+
+	data, err := PackWithOptions(sampleLedger, sampleConstantMemory, sampleTypeInfo, options)
+	if err != nil {
+		t.Fatalf("pack: %v", err)
+	}
+
+	return data
+}
+
+func unpackData(t *testing.T, data []byte) *Contents {
+	t.Helper()
+
+	contents, err := Unpack(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unpack: %v", err)
+	}
+
+	return contents
+}
+
+func checkSampleContents(t *testing.T, contents *Contents) {
+	t.Helper()
+
+	if !bytes.Equal(contents.Ledger, sampleLedger) {
+		t.Errorf("ledger is %x, expected %x", contents.Ledger, sampleLedger)
+	}
+
+	if !bytes.Equal(contents.ConstantMemory, sampleConstantMemory) {
+		t.Errorf("constant memory is %x, expected %x", contents.ConstantMemory, sampleConstantMemory)
+	}
+
+	if !bytes.Equal(contents.TypeInfo, sampleTypeInfo) {
+		t.Errorf("type info is %x, expected %x", contents.TypeInfo, sampleTypeInfo)
+	}
+}
+
+func TestBlockAlign(t *testing.T) {
+	for _, options := range []Options{
+		{BlockAlign: 1},
+		{BlockAlign: 16},
+		{BlockAlign: 512},
+		{BlockAlign: 512, ChunkChecksums: true},
+	} {
+		data := packSample(t, options)
+		if len(data)%options.BlockAlign != 0 {
+			t.Errorf("pack is %d octets, not a multiple of %d", len(data), options.BlockAlign)
+		}
+
+		checkSampleContents(t, unpackData(t, data))
+	}
+}
+
+func TestBlockAlignNegative(t *testing.T) {
+	if _, err := PackWithOptions(sampleLedger, sampleConstantMemory, sampleTypeInfo, Options{BlockAlign: -1}); err == nil {
+		t.Fatal("expected an error for a negative block align")
+	}
+}
+
+func TestEstimatedSize(t *testing.T) {
+	for _, options := range []Options{
+		{},
+		{BlockAlign: 512},
+		{ChunkChecksums: true, ChunkIndex: true},
+		{Metadata: map[string]string{"commit": "3eb4de7"}, SourceFiles: []string{"main.swamp"}},
+	} {
+		octetCount, err := EstimatedSize(sampleLedger, sampleConstantMemory, sampleTypeInfo, options)
+		if err != nil {
+			t.Fatalf("estimated size: %v", err)
+		}
+
+		data := packSample(t, options)
+		if octetCount != len(data) {
+			t.Errorf("estimated size is %d, pack is %d octets", octetCount, len(data))
+		}
+
+		if cap(data) != len(data) {
+			t.Errorf("pack buffer was regrown to capacity %d for %d octets", cap(data), len(data))
+		}
+	}
+}
+
+func TestEstimatedSizeNegativeBlockAlign(t *testing.T) {
+	if _, err := EstimatedSize(sampleLedger, sampleConstantMemory, sampleTypeInfo, Options{BlockAlign: -1}); err == nil {
+		t.Fatal("expected an error for a negative block align")
+	}
+}
+
+func BenchmarkPackWithOptions(b *testing.B) {
+	ledger := bytes.Repeat(sampleLedger, 16*1024)
+	options := Options{BlockAlign: 512, ChunkChecksums: true}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(ledger)))
+
+	for i := 0; i < b.N; i++ {
+		if _, err := PackWithOptions(ledger, sampleConstantMemory, sampleTypeInfo, options); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// packAllocationBudget is the most allocations Pack may make for a pack without optional chunks.
+// Raise it only together with a reason in the commit that needs it.
+const packAllocationBudget = 32
+
+func TestPackAllocations(t *testing.T) {
+	ledger, constantMemory := representativeSections()
+
+	allocations := testing.AllocsPerRun(20, func() {
+		if _, err := Pack(ledger, constantMemory, sampleTypeInfo); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if allocations > packAllocationBudget {
+		t.Errorf("Pack made %v allocations, the budget is %d", allocations, packAllocationBudget)
+	}
+}
+
+func BenchmarkPack(b *testing.B) {
+	ledger, constantMemory := representativeSections()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(ledger) + len(constantMemory)))
+
+	for i := 0; i < b.N; i++ {
+		if _, err := Pack(ledger, constantMemory, sampleTypeInfo); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+var errWriteFailed = errors.New("write failed")
+
+// failingWriter accepts octetCount octets and then fails every write.
+type failingWriter struct {
+	octetCount int
+}
+
+func (w *failingWriter) Write(octets []byte) (int, error) {
+	if len(octets) <= w.octetCount {
+		w.octetCount -= len(octets)
+		return len(octets), nil
+	}
+
+	written := w.octetCount
+	w.octetCount = 0
+
+	return written, errWriteFailed
+}
+
+func TestChunkWriteError(t *testing.T) {
+	payload := bytes.Repeat([]byte{0xaa}, 100)
+
+	for _, octetCount := range []int{0, 5, chunkHeaderOctetCount, chunkHeaderOctetCount + 60} {
+		err := writeChunkHeader(&failingWriter{octetCount: octetCount}, IconLedger, ledgerName, payload)
+
+		var writeErr *ChunkWriteError
+		if !errors.As(err, &writeErr) {
+			t.Fatalf("expected a ChunkWriteError after %d octets, got %v", octetCount, err)
+		}
+
+		if writeErr.Name != ledgerName || writeErr.Written != int64(octetCount) {
+			t.Errorf("error is for '%v' after %d octets, expected 'ldg0' after %d", raff.NameToString(writeErr.Name),
+				writeErr.Written, octetCount)
+		}
+
+		if !errors.Is(err, errWriteFailed) {
+			t.Errorf("expected the write error to unwrap to errWriteFailed, got %v", err)
+		}
+	}
+}
+
+func TestChunkWriteErrorFromArchive(t *testing.T) {
+	archive := NewArchive()
+	archive.AddPack("game", packSample(t, Options{}))
+
+	var writeErr *ChunkWriteError
+	if err := archive.WriteArchive(&failingWriter{octetCount: 40}); !errors.As(err, &writeErr) {
+		t.Fatalf("expected a ChunkWriteError, got %v", err)
+	}
+
+	if writeErr.Name != archiveEntryName {
+		t.Errorf("error is for chunk '%v', expected the archive entry", raff.NameToString(writeErr.Name))
+	}
+}
+
+// dirtyPool hands out reused buffers filled with junk, so packing must not depend on zeroed buffers.
+type dirtyPool struct {
+	free     [][]byte
+	getCount int
+	putCount int
+}
+
+func (p *dirtyPool) Get(n int) []byte {
+	p.getCount++
+
+	for index, buffer := range p.free {
+		if cap(buffer) >= n {
+			p.free = append(p.free[:index], p.free[index+1:]...)
+			return buffer[:cap(buffer)]
+		}
+	}
+
+	return bytes.Repeat([]byte{0xff}, n)
+}
+
+func (p *dirtyPool) Put(buffer []byte) {
+	p.putCount++
+
+	buffer = buffer[:cap(buffer)]
+	for index := range buffer {
+		buffer[index] = 0xee
+	}
+
+	p.free = append(p.free, buffer)
+}
+
+func TestBufferPool(t *testing.T) {
+	pool := &dirtyPool{}
+
+	for _, options := range []Options{
+		{},
+		{BlockAlign: 512, ChunkChecksums: true, ChunkIndex: true},
+		{BlockAlign: 64, ChunkChecksums: true, Metadata: map[string]string{"k": "v"}},
+	} {
+		expected := packSample(t, options)
+
+		for round := 0; round < 3; round++ {
+			pooled := options
+			pooled.BufferPool = pool
+
+			data := packSample(t, pooled)
+			if !bytes.Equal(data, expected) {
+				t.Fatalf("pack with a buffer pool differs from the pack without one for %+v", options)
+			}
+
+			pool.Put(data)
+		}
+	}
+
+	if pool.getCount != pool.putCount {
+		t.Errorf("%d buffers were taken from the pool and %d handed back", pool.getCount, pool.putCount)
+	}
+}
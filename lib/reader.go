@@ -0,0 +1,105 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"fmt"
+	"io"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+// sliceReader reads a pack that is already in memory, and hands out chunk payloads as
+// sub-slices of it instead of copying them.
+type sliceReader struct {
+	octets []byte
+	offset int
+}
+
+func (r *sliceReader) Read(target []byte) (int, error) {
+	if r.offset >= len(r.octets) {
+		return 0, io.EOF
+	}
+
+	octetCount := copy(target, r.octets[r.offset:])
+	r.offset += octetCount
+
+	return octetCount, nil
+}
+
+func (r *sliceReader) readChunkPayload(header raff.ChunkHeader) ([]byte, error) {
+	if int(header.OctetCount) > len(r.octets)-r.offset {
+		r.offset = len(r.octets)
+		return nil, fmt.Errorf("chunk '%v' %w", raff.NameToString(header.Name), io.ErrUnexpectedEOF)
+	}
+
+	payload := r.octets[r.offset : r.offset+int(header.OctetCount) : r.offset+int(header.OctetCount)]
+	r.offset += int(header.OctetCount)
+
+	return payload, nil
+}
+
+// reuseOctets copies payload into buffer. A present but empty payload stays non-nil, like in Unpack.
+func reuseOctets(buffer []byte, payload []byte) []byte {
+	if payload == nil {
+		return nil
+	}
+
+	if buffer == nil {
+		buffer = make([]byte, 0, len(payload))
+	}
+
+	return append(buffer, payload...)
+}
+
+// Reader unpacks many packs in a row, reusing the buffers of the Contents it unpacks into.
+type Reader struct {
+	source sliceReader
+}
+
+func NewReader() *Reader {
+	return &Reader{}
+}
+
+// Unpack reads the pack in data into the contents into, which is cleared first. The TypeInfo,
+// ConstantMemory and Ledger payloads are copied into the backing arrays into already has, so they
+// only allocate when a payload is larger than before. Any slice previously taken from into is
+// overwritten by the next call. CustomChunks payloads are not copied and refer to data, so data
+// must not be modified while they are in use. On error into is left cleared.
+func (r *Reader) Unpack(data []byte, into *Contents) error {
+	typeInfo := into.TypeInfo[:0]
+	constantMemory := into.ConstantMemory[:0]
+	ledger := into.Ledger[:0]
+	customChunks := into.CustomChunks[:0]
+
+	*into = Contents{}
+
+	r.source = sliceReader{octets: data}
+
+	var firstErr error
+
+	contents := unpack(&r.source, UnpackOptions{}, func(err error) bool {
+		firstErr = err
+		return false
+	})
+
+	r.source = sliceReader{}
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	*into = *contents
+	into.TypeInfo = reuseOctets(typeInfo, contents.TypeInfo)
+	into.ConstantMemory = reuseOctets(constantMemory, contents.ConstantMemory)
+	into.Ledger = reuseOctets(ledger, contents.Ledger)
+
+	if contents.CustomChunks != nil {
+		into.CustomChunks = append(customChunks, contents.CustomChunks...)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReaderUnpack(t *testing.T) {
+	reader := NewReader()
+
+	var contents Contents
+	if err := reader.Unpack(packSample(t, Options{ChunkChecksums: true}), &contents); err != nil {
+		t.Fatalf("reader unpack: %v", err)
+	}
+
+	checkSampleContents(t, &contents)
+	ledger := contents.Ledger
+
+	other, err := PackWithOptions([]byte{0x09, 0x08}, sampleConstantMemory, sampleTypeInfo, Options{})
+	if err != nil {
+		t.Fatalf("pack: %v", err)
+	}
+
+	if err := reader.Unpack(other, &contents); err != nil {
+		t.Fatalf("reader unpack: %v", err)
+	}
+
+	if !bytes.Equal(contents.Ledger, []byte{0x09, 0x08}) {
+		t.Errorf("ledger is %x", contents.Ledger)
+	}
+
+	if &contents.Ledger[0] != &ledger[0] {
+		t.Error("the ledger buffer was not reused")
+	}
+}
+
+func TestReaderKeepsEmptyPayloads(t *testing.T) {
+	data, err := Pack([]byte{}, []byte{}, []byte{})
+	if err != nil {
+		t.Fatalf("pack: %v", err)
+	}
+
+	var contents Contents
+	if err := NewReader().Unpack(data, &contents); err != nil {
+		t.Fatalf("reader unpack: %v", err)
+	}
+
+	if contents.TypeInfo == nil || contents.ConstantMemory == nil || contents.Ledger == nil {
+		t.Errorf("empty payloads should be non-nil: %+v", contents)
+	}
+
+	unpacked := unpackData(t, data)
+	if unpacked.TypeInfo == nil || unpacked.ConstantMemory == nil || unpacked.Ledger == nil {
+		t.Errorf("Unpack should also keep empty payloads non-nil: %+v", unpacked)
+	}
+}
+
+func TestReaderClearsOnError(t *testing.T) {
+	contents := Contents{Metadata: map[string]string{"stale": "yes"}}
+
+	if err := NewReader().Unpack([]byte("not a pack"), &contents); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if contents.Metadata != nil {
+		t.Errorf("contents were not cleared: %+v", contents)
+	}
+}
+
+func BenchmarkUnpack(b *testing.B) {
+	ledger, constantMemory := representativeSections()
+
+	data, err := Pack(ledger, constantMemory, sampleTypeInfo)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("Unpack", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			if _, err := Unpack(bytes.NewReader(data)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Reader", func(b *testing.B) {
+		b.ReportAllocs()
+
+		reader := NewReader()
+
+		var contents Contents
+
+		for i := 0; i < b.N; i++ {
+			if err := reader.Unpack(data, &contents); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,39 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+var ErrSchemaMismatch = errors.New("pack type schema mismatch")
+
+// SchemaFingerprint is the first 16 octets of the SHA-256 of the type-info payload.
+func SchemaFingerprint(typeInfo []byte) [16]byte {
+	hash := sha256.Sum256(typeInfo)
+
+	var fingerprint [16]byte
+	copy(fingerprint[:], hash[:])
+
+	return fingerprint
+}
+
+// RequireSchema returns ErrSchemaMismatch unless the type-info of the pack has the fingerprint want.
+func RequireSchema(data []byte, want [16]byte) error {
+	contents, unpackErr := Unpack(bytes.NewReader(data))
+	if unpackErr != nil {
+		return unpackErr
+	}
+
+	if got := SchemaFingerprint(contents.TypeInfo); got != want {
+		return fmt.Errorf("%w: pack has %x, expected %x", ErrSchemaMismatch, got, want)
+	}
+
+	return nil
+}
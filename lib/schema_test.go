@@ -0,0 +1,30 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRequireSchema(t *testing.T) {
+	data := packSample(t, Options{})
+
+	if err := RequireSchema(data, SchemaFingerprint(sampleTypeInfo)); err != nil {
+		t.Fatalf("require schema: %v", err)
+	}
+}
+
+func TestRequireSchemaMismatch(t *testing.T) {
+	data := packSample(t, Options{})
+
+	changedTypeInfo := append([]byte{}, sampleTypeInfo...)
+	changedTypeInfo[len(changedTypeInfo)-1] ^= 0x01
+
+	if err := RequireSchema(data, SchemaFingerprint(changedTypeInfo)); !errors.Is(err, ErrSchemaMismatch) {
+		t.Fatalf("expected ErrSchemaMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+const IconSignature raff.FourOctets = 0xF09F948F // 🔏
+
+var signatureName = raff.MakeFourOctets('s', 'i', 'g', '0')
+
+var (
+	ErrNotSigned         = errors.New("pack is not signed")
+	ErrAlreadySigned     = errors.New("pack is already signed")
+	ErrSignatureMismatch = errors.New("pack signature does not match")
+	ErrSignatureNotLast  = errors.New("signature chunk must be the last chunk")
+	ErrInvalidKey        = errors.New("invalid signature key")
+)
+
+type signatureScan struct {
+	flags           uint32
+	signatureOffset int
+	signature       []byte
+}
+
+// scanSignature finds the signature chunk, which must be the last chunk of the pack.
+func scanSignature(data []byte, limits Limits) (signatureScan, error) {
+	reader := &sliceReader{octets: data}
+	chunkReader := newLimitedChunkReader(reader, limits)
+
+	flags, flagsErr := readPackHeader(chunkReader)
+	if flagsErr != nil {
+		return signatureScan{}, flagsErr
+	}
+
+	scan := signatureScan{flags: flags, signatureOffset: -1}
+
+	for {
+		offset := reader.offset
+
+		header, payload, readErr := chunkReader.readChunk()
+		if errors.Is(readErr, io.EOF) {
+			return scan, nil
+		}
+
+		if readErr != nil {
+			return signatureScan{}, fmt.Errorf("signature read chunk %w", readErr)
+		}
+
+		if scan.signatureOffset >= 0 {
+			return signatureScan{}, ErrSignatureNotLast
+		}
+
+		if header.Name != signatureName || header.Icon == IconDead {
+			continue
+		}
+
+		if err := checkIcon(header, IconSignature); err != nil {
+			return signatureScan{}, err
+		}
+
+		checkedPayload, checksumErr := chunkReader.checkedPayload(header, payload)
+		if checksumErr != nil {
+			return signatureScan{}, checksumErr
+		}
+
+		scan.signatureOffset = offset
+		scan.signature = checkedPayload
+	}
+}
+
+// Sign appends a sig0 chunk with an Ed25519 signature over all octets of the pack.
+// Readers that do not check signatures load a signed pack as before.
+func Sign(data []byte, privateKey ed25519.PrivateKey) ([]byte, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%w: private key has %d octets, expected %d", ErrInvalidKey, len(privateKey),
+			ed25519.PrivateKeySize)
+	}
+
+	scan, scanErr := scanSignature(data, Limits{})
+	if scanErr != nil {
+		return nil, scanErr
+	}
+
+	if scan.signatureOffset >= 0 {
+		return nil, ErrAlreadySigned
+	}
+
+	options := Options{ChunkChecksums: scan.flags&packFlagChunkChecksums != 0}
+
+	buf := bytes.NewBuffer(append([]byte{}, data...))
+	if err := options.writeChunk(buf, IconSignature, signatureName, ed25519.Sign(privateKey, data)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// VerifySignature checks that the sig0 chunk holds a valid signature by publicKey over all
+// octets that precede it.
+func VerifySignature(data []byte, publicKey ed25519.PublicKey) error {
+	return verifySignature(data, publicKey, Limits{})
+}
+
+func verifySignature(data []byte, publicKey ed25519.PublicKey, limits Limits) error {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: public key has %d octets, expected %d", ErrInvalidKey, len(publicKey),
+			ed25519.PublicKeySize)
+	}
+
+	scan, scanErr := scanSignature(data, limits)
+	if scanErr != nil {
+		return scanErr
+	}
+
+	if scan.signatureOffset < 0 {
+		return ErrNotSigned
+	}
+
+	if !ed25519.Verify(publicKey, data[:scan.signatureOffset], scan.signature) {
+		return ErrSignatureMismatch
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+)
+
+func TestSignatureRoundTrip(t *testing.T) {
+	privateKey := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x42}, ed25519.SeedSize))
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+
+	for _, options := range []Options{{}, {ChunkChecksums: true, BlockAlign: 64}} {
+		signed, err := Sign(packSample(t, options), privateKey)
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+
+		if err := VerifySignature(signed, publicKey); err != nil {
+			t.Errorf("verify signature: %v", err)
+		}
+
+		contents, unpackErr := UnpackWithOptions(bytes.NewReader(signed), UnpackOptions{PublicKey: publicKey})
+		if unpackErr != nil {
+			t.Fatalf("unpack: %v", unpackErr)
+		}
+
+		checkSampleContents(t, contents)
+	}
+}
+
+func TestSignatureMismatch(t *testing.T) {
+	signed, publicKey := signedSample(t)
+
+	tampered := append([]byte{}, signed...)
+	tampered[bytes.Index(tampered, sampleLedger)] ^= 0x01
+
+	if err := VerifySignature(tampered, publicKey); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("tampered pack: expected ErrSignatureMismatch, got %v", err)
+	}
+
+	_, unpackErr := UnpackWithOptions(bytes.NewReader(tampered), UnpackOptions{PublicKey: publicKey})
+	if !errors.Is(unpackErr, ErrSignatureMismatch) {
+		t.Errorf("unpack tampered pack: expected ErrSignatureMismatch, got %v", unpackErr)
+	}
+
+	otherKey := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x43}, ed25519.SeedSize)).Public().(ed25519.PublicKey)
+	if err := VerifySignature(signed, otherKey); !errors.Is(err, ErrSignatureMismatch) {
+		t.Errorf("other key: expected ErrSignatureMismatch, got %v", err)
+	}
+}
+
+func TestNotSigned(t *testing.T) {
+	_, publicKey := signedSample(t)
+	data := packSample(t, Options{})
+
+	if err := VerifySignature(data, publicKey); !errors.Is(err, ErrNotSigned) {
+		t.Errorf("expected ErrNotSigned, got %v", err)
+	}
+
+	if _, err := UnpackWithOptions(bytes.NewReader(data), UnpackOptions{PublicKey: publicKey}); !errors.Is(err, ErrNotSigned) {
+		t.Errorf("unpack: expected ErrNotSigned, got %v", err)
+	}
+}
+
+func TestAlreadySigned(t *testing.T) {
+	signed, _ := signedSample(t)
+	privateKey := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x42}, ed25519.SeedSize))
+
+	if _, err := Sign(signed, privateKey); !errors.Is(err, ErrAlreadySigned) {
+		t.Fatalf("expected ErrAlreadySigned, got %v", err)
+	}
+}
+
+func TestSignatureMustBeLast(t *testing.T) {
+	signed, publicKey := signedSample(t)
+
+	var appended bytes.Buffer
+	appended.Write(signed)
+
+	if err := writeChunkHeader(&appended, IconMetadata, metadataName, []byte{0, 0, 0, 0}); err != nil {
+		t.Fatalf("write chunk: %v", err)
+	}
+
+	if err := VerifySignature(appended.Bytes(), publicKey); !errors.Is(err, ErrSignatureNotLast) {
+		t.Errorf("expected ErrSignatureNotLast, got %v", err)
+	}
+
+	privateKey := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x42}, ed25519.SeedSize))
+	if _, err := Sign(appended.Bytes(), privateKey); !errors.Is(err, ErrSignatureNotLast) {
+		t.Errorf("sign: expected ErrSignatureNotLast, got %v", err)
+	}
+}
+
+func TestInvalidKeyLength(t *testing.T) {
+	signed, _ := signedSample(t)
+	shortKey := ed25519.PublicKey{1, 2, 3, 4, 5}
+
+	if err := VerifySignature(signed, shortKey); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("verify signature: expected ErrInvalidKey, got %v", err)
+	}
+
+	if _, err := UnpackWithOptions(bytes.NewReader(signed), UnpackOptions{PublicKey: shortKey}); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("unpack: expected ErrInvalidKey, got %v", err)
+	}
+
+	if _, err := Sign(packSample(t, Options{}), ed25519.PrivateKey{1, 2, 3}); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("sign: expected ErrInvalidKey, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+const (
+	IconSourceFiles raff.FourOctets = 0xF09F9782 // 🗂
+	IconSourceHash  raff.FourOctets = 0xF09FA7BE // 🧾
+)
+
+var (
+	ErrSourceHashMismatch = errors.New("source hash mismatch")
+	sourceFilesName       = raff.MakeFourOctets('s', 'r', 'c', '0')
+	sourceHashName        = raff.MakeFourOctets('s', 'r', 'h', '0')
+)
+
+func sourceFilesPayload(paths []string) ([]byte, error) {
+	var payload bytes.Buffer
+
+	binary.Write(&payload, binary.BigEndian, uint32(len(paths)))
+	for index, path := range paths {
+		if err := writeString(&payload, path); err != nil {
+			return nil, fmt.Errorf("source file %d %w", index, err)
+		}
+	}
+
+	return payload.Bytes(), nil
+}
+
+func readSourceFiles(payload []byte) ([]string, error) {
+	reader := bytes.NewReader(payload)
+
+	var count uint32
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, fmt.Errorf("source files count %w", err)
+	}
+
+	if int64(count)*2 > int64(reader.Len()) {
+		return nil, fmt.Errorf("source files count %d does not fit in chunk", count)
+	}
+
+	paths := make([]string, count)
+	for index := range paths {
+		path, pathErr := readString(reader)
+		if pathErr != nil {
+			return nil, fmt.Errorf("source file %d %w", index, pathErr)
+		}
+
+		paths[index] = path
+	}
+
+	return paths, nil
+}
+
+func readSourceHash(payload []byte) (*[sha256.Size]byte, error) {
+	if len(payload) != sha256.Size {
+		return nil, fmt.Errorf("source hash chunk has %d octets, expected %d", len(payload), sha256.Size)
+	}
+
+	var hash [sha256.Size]byte
+	copy(hash[:], payload)
+
+	return &hash, nil
+}
+
+// RequireSourceHash returns ErrSourceHashMismatch unless the contents were packed with the source hash want.
+func RequireSourceHash(contents *Contents, want [sha256.Size]byte) error {
+	if contents.SourceHash == nil {
+		return fmt.Errorf("%w: pack has no source hash, expected %x", ErrSourceHashMismatch, want)
+	}
+
+	if got := *contents.SourceHash; got != want {
+		return fmt.Errorf("%w: pack has %x, expected %x", ErrSourceHashMismatch, got, want)
+	}
+
+	return nil
+}
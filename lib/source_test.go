@@ -0,0 +1,51 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSourceHashRoundTrip(t *testing.T) {
+	hash := sha256.Sum256([]byte("func main() {}"))
+
+	contents := unpackData(t, packSample(t, Options{SourceHash: &hash}))
+	checkSampleContents(t, contents)
+
+	if err := RequireSourceHash(contents, hash); err != nil {
+		t.Fatalf("require source hash: %v", err)
+	}
+
+	if err := RequireSourceHash(contents, sha256.Sum256([]byte("func main() { }"))); !errors.Is(err, ErrSourceHashMismatch) {
+		t.Errorf("expected ErrSourceHashMismatch, got %v", err)
+	}
+}
+
+func TestSourceHashIsOptional(t *testing.T) {
+	hash := sha256.Sum256(nil)
+	contents := unpackData(t, packSample(t, Options{}))
+
+	if err := RequireSourceHash(contents, hash); !errors.Is(err, ErrSourceHashMismatch) {
+		t.Errorf("expected ErrSourceHashMismatch for a pack without a source hash, got %v", err)
+	}
+
+	if !bytes.Equal(packSample(t, Options{}), packSample(t, Options{SourceHash: nil})) {
+		t.Error("an unset source hash changed the pack")
+	}
+}
+
+func TestSourceFilesRoundTrip(t *testing.T) {
+	paths := []string{"main.swamp", "lib/math.swamp", ""}
+
+	contents := unpackData(t, packSample(t, Options{SourceFiles: paths}))
+	if !reflect.DeepEqual(contents.SourceFiles, paths) {
+		t.Errorf("source files are %q, expected %q", contents.SourceFiles, paths)
+	}
+}
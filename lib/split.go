@@ -0,0 +1,140 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+const IconExternalConstantMemory raff.FourOctets = 0xF09F9497 // 🔗
+
+// externalConstantMemoryName holds the SHA-256 of the constant memory file that a split pack was written with.
+var externalConstantMemoryName = raff.MakeFourOctets('d', 'm', 'x', '1')
+
+var (
+	ErrNotSplit               = errors.New("pack does not reference an external constant memory file")
+	ErrConstantMemoryFileHash = errors.New("constant memory file does not match the pack")
+)
+
+// referencedChunkName is the uncompressed name of a chunk, with dmx1 standing in for the dme1 it refers to.
+func referencedChunkName(name raff.FourOctets) raff.FourOctets {
+	name = uncompressedChunkName(name)
+	if name == externalConstantMemoryName {
+		return constantMemoryName
+	}
+
+	return name
+}
+
+// PackSplit writes the constant memory to its own file, so it can be loaded or memory mapped on its own.
+// The main pack holds everything else and the SHA-256 of the constant memory file.
+func PackSplit(ledger []byte, constantMemory []byte, typeInfo []byte) ([]byte, []byte, error) {
+	var constants bytes.Buffer
+
+	if err := raff.WriteHeader(&constants); err != nil {
+		return nil, nil, fmt.Errorf("pack split write header %w", err)
+	}
+
+	if err := writePackHeader(&constants, 0); err != nil {
+		return nil, nil, err
+	}
+
+	if err := writeConstantMemory(&constants, constantMemory); err != nil {
+		return nil, nil, err
+	}
+
+	var main bytes.Buffer
+
+	if err := raff.WriteHeader(&main); err != nil {
+		return nil, nil, fmt.Errorf("pack split write header %w", err)
+	}
+
+	if err := writePackHeader(&main, 0); err != nil {
+		return nil, nil, err
+	}
+
+	if err := writeTypeInfo(&main, typeInfo); err != nil {
+		return nil, nil, err
+	}
+
+	hash := sha256.Sum256(constants.Bytes())
+	if err := writeChunkHeader(&main, IconExternalConstantMemory, externalConstantMemoryName, hash[:]); err != nil {
+		return nil, nil, err
+	}
+
+	if err := writeLedger(&main, ledger); err != nil {
+		return nil, nil, err
+	}
+
+	return main.Bytes(), constants.Bytes(), nil
+}
+
+func readExternalConstantMemoryHash(main []byte) ([]byte, uint32, error) {
+	chunkReader := newLimitedChunkReader(bytes.NewReader(main), Limits{})
+
+	flags, flagsErr := readPackHeader(chunkReader)
+	if flagsErr != nil {
+		return nil, 0, flagsErr
+	}
+
+	for {
+		header, payload, readErr := chunkReader.readChunk()
+		if errors.Is(readErr, io.EOF) {
+			return nil, 0, ErrNotSplit
+		}
+
+		if readErr != nil {
+			return nil, 0, fmt.Errorf("unpack split read chunk %w", readErr)
+		}
+
+		if header.Name != externalConstantMemoryName || header.Icon == IconDead {
+			continue
+		}
+
+		if err := checkIcon(header, IconExternalConstantMemory); err != nil {
+			return nil, 0, err
+		}
+
+		checkedPayload, checksumErr := chunkReader.checkedPayload(header, payload)
+		if checksumErr != nil {
+			return nil, 0, checksumErr
+		}
+
+		return checkedPayload, flags, nil
+	}
+}
+
+// UnpackSplit checks that constants is the constant memory file main was written with and unpacks them together.
+func UnpackSplit(main []byte, constants []byte) (*Contents, error) {
+	expectedHash, flags, hashErr := readExternalConstantMemoryHash(main)
+	if hashErr != nil {
+		return nil, hashErr
+	}
+
+	if hash := sha256.Sum256(constants); !bytes.Equal(hash[:], expectedHash) {
+		return nil, ErrConstantMemoryFileHash
+	}
+
+	constantMemory, constantMemoryErr := UnpackConstantMemory(constants)
+	if constantMemoryErr != nil {
+		return nil, constantMemoryErr
+	}
+
+	options := Options{ChunkChecksums: flags&packFlagChunkChecksums != 0}
+
+	combined := bytes.NewBuffer(append([]byte{}, main...))
+	if err := options.writeChunk(combined, IconConstantMemory, constantMemoryName, constantMemory); err != nil {
+		return nil, err
+	}
+
+	return Unpack(combined)
+}
@@ -0,0 +1,96 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPackSplitRoundTrip(t *testing.T) {
+	main, constants, err := PackSplit(sampleLedger, sampleConstantMemory, sampleTypeInfo)
+	if err != nil {
+		t.Fatalf("pack split: %v", err)
+	}
+
+	contents, unpackErr := UnpackSplit(main, constants)
+	if unpackErr != nil {
+		t.Fatalf("unpack split: %v", unpackErr)
+	}
+
+	checkSampleContents(t, contents)
+}
+
+func TestUnpackSplitWrongConstants(t *testing.T) {
+	main, _, err := PackSplit(sampleLedger, sampleConstantMemory, sampleTypeInfo)
+	if err != nil {
+		t.Fatalf("pack split: %v", err)
+	}
+
+	_, otherConstants, otherErr := PackSplit(sampleLedger, []byte{0x40}, sampleTypeInfo)
+	if otherErr != nil {
+		t.Fatalf("pack split: %v", otherErr)
+	}
+
+	if _, err := UnpackSplit(main, otherConstants); !errors.Is(err, ErrConstantMemoryFileHash) {
+		t.Fatalf("expected ErrConstantMemoryFileHash, got %v", err)
+	}
+}
+
+func TestUnpackSplitNotSplit(t *testing.T) {
+	data := packSample(t, Options{})
+
+	if _, err := UnpackSplit(data, data); !errors.Is(err, ErrNotSplit) {
+		t.Fatalf("expected ErrNotSplit, got %v", err)
+	}
+}
+
+func TestSplitMainThroughRewriteTools(t *testing.T) {
+	main, constants, err := PackSplit(sampleLedger, sampleConstantMemory, sampleTypeInfo)
+	if err != nil {
+		t.Fatalf("pack split: %v", err)
+	}
+
+	if err := Verify(bytes.NewReader(main)); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	if err := VerifyStream(bytes.NewReader(main), DefaultLimits()); err != nil {
+		t.Fatalf("verify stream: %v", err)
+	}
+
+	if _, err := UnpackWithOptions(bytes.NewReader(main), UnpackOptions{RequireCanonicalOrder: true}); err != nil {
+		t.Fatalf("unpack in canonical order: %v", err)
+	}
+
+	if contents := unpackData(t, main); contents.ConstantMemory != nil {
+		t.Errorf("main pack unpacked constant memory %x", contents.ConstantMemory)
+	}
+
+	stripped, stripErr := Strip(main)
+	if stripErr != nil {
+		t.Fatalf("strip: %v", stripErr)
+	}
+
+	canonical, canonicalErr := Canonicalize(main)
+	if canonicalErr != nil {
+		t.Fatalf("canonicalize: %v", canonicalErr)
+	}
+
+	if !bytes.Equal(canonical, main) {
+		t.Error("canonicalizing the main pack changed it")
+	}
+
+	for _, rewritten := range [][]byte{stripped, canonical} {
+		contents, unpackErr := UnpackSplit(rewritten, constants)
+		if unpackErr != nil {
+			t.Fatalf("unpack split: %v", unpackErr)
+		}
+
+		checkSampleContents(t, contents)
+	}
+}
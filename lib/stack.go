@@ -0,0 +1,39 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrStackImbalance = errors.New("stack imbalance")
+
+// VerifyStackBalance simulates the stack depth of the opcodes of one function using the stack effects
+// in table. The opcodes are treated as straight-line code. It fails if the stack would go below zero,
+// or if returnCount values are not left on the stack at the end.
+func VerifyStackBalance(opcodes []byte, table OpcodeTable, returnCount int) error {
+	depth := 0
+
+	if err := forEachInstruction(opcodes, table, func(decoded decodedInstruction) error {
+		if decoded.instruction.StackPops > depth {
+			return fmt.Errorf("%w: '%v' at offset %04X pops %d with %d on the stack", ErrStackImbalance,
+				decoded.instruction.Mnemonic, decoded.offset, decoded.instruction.StackPops, depth)
+		}
+
+		depth += decoded.instruction.StackPushes - decoded.instruction.StackPops
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if depth != returnCount {
+		return fmt.Errorf("%w: %d on the stack at the end, expected %d", ErrStackImbalance, depth, returnCount)
+	}
+
+	return nil
+}
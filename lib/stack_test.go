@@ -0,0 +1,63 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"errors"
+	"testing"
+)
+
+const (
+	opcodeLoadConstant byte = 0x01
+	opcodePushInt      byte = 0x02
+	opcodeAdd          byte = 0x03
+	opcodePop          byte = 0x04
+	opcodeReturn       byte = 0x05
+)
+
+var testOpcodeTable = OpcodeTable{
+	opcodeLoadConstant: {Mnemonic: "ld", Operands: []Operand{{OctetCount: 2, ConstantRef: true, Alignment: 2}}, StackPushes: 1},
+	opcodePushInt:      {Mnemonic: "push", Operands: []Operand{{OctetCount: 4, Alignment: 4}}, StackPushes: 1},
+	opcodeAdd:          {Mnemonic: "add", StackPops: 2, StackPushes: 1},
+	opcodePop:          {Mnemonic: "pop", StackPops: 1},
+	opcodeReturn:       {Mnemonic: "ret"},
+}
+
+func TestVerifyStackBalance(t *testing.T) {
+	opcodes := []byte{
+		opcodeLoadConstant, 0x00, 0x01,
+		opcodeLoadConstant, 0x00, 0x02,
+		opcodeAdd,
+		opcodeReturn,
+	}
+
+	if err := VerifyStackBalance(opcodes, testOpcodeTable, 1); err != nil {
+		t.Fatalf("verify stack balance: %v", err)
+	}
+}
+
+func TestVerifyStackBalanceUnbalanced(t *testing.T) {
+	for _, test := range []struct {
+		opcodes     []byte
+		returnCount int
+	}{
+		{[]byte{opcodeLoadConstant, 0x00, 0x01, opcodeAdd, opcodeReturn}, 1},
+		{[]byte{opcodePop, opcodeReturn}, 0},
+		{[]byte{opcodeLoadConstant, 0x00, 0x01, opcodeLoadConstant, 0x00, 0x02, opcodeReturn}, 1},
+		{[]byte{opcodeReturn}, 1},
+	} {
+		if err := VerifyStackBalance(test.opcodes, testOpcodeTable, test.returnCount); !errors.Is(err, ErrStackImbalance) {
+			t.Errorf("expected ErrStackImbalance for % x, got %v", test.opcodes, err)
+		}
+	}
+}
+
+func TestVerifyStackBalanceUnknownOpcode(t *testing.T) {
+	err := VerifyStackBalance([]byte{0xff}, testOpcodeTable, 0)
+	if err == nil || errors.Is(err, ErrStackImbalance) {
+		t.Fatalf("expected an unknown opcode error, got %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+var ErrDebugInfoMismatch = errors.New("debug info is from a different build")
+
+// rewriteChunks copies the pack header chunk and every following chunk that keepChunk accepts.
+// A chunk index is always dropped, since it would no longer match the chunks. A signature is
+// dropped once any chunk before it has been, so the result has to be signed again.
+func rewriteChunks(data []byte, keepChunk func(header raff.ChunkHeader, payload []byte) bool) ([]byte, error) {
+	reader := &sliceReader{octets: data}
+	if err := readFileHeader(reader); err != nil {
+		return nil, err
+	}
+
+	chunkReader := newLimitedChunkReader(reader, Limits{})
+
+	var buf bytes.Buffer
+
+	if err := raff.WriteHeader(&buf); err != nil {
+		return nil, fmt.Errorf("rewrite write header %w", err)
+	}
+
+	isPackHeader := true
+	wasChunkDropped := false
+
+	for {
+		header, payload, readErr := chunkReader.readChunk()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+
+		if readErr != nil {
+			return nil, fmt.Errorf("rewrite read chunk %w", readErr)
+		}
+
+		isStaleSignature := header.Name == signatureName && header.Icon != IconDead && wasChunkDropped
+		if !isPackHeader && (header.Name == chunkIndexName || isStaleSignature || !keepChunk(header, payload)) {
+			wasChunkDropped = true
+			continue
+		}
+
+		isPackHeader = false
+
+		if err := writeChunkHeader(&buf, header.Icon, header.Name, payload); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Strip returns a copy of the pack with only the mandatory chunks and the chunks named in keep.
+// The dmx1 reference of a split main pack is kept like dme1. Dead chunks are always removed.
+func Strip(data []byte, keep ...raff.FourOctets) ([]byte, error) {
+	keepNames := append(append([]raff.FourOctets{externalConstantMemoryName}, mandatoryChunkNames...), keep...)
+
+	return rewriteChunks(data, func(header raff.ChunkHeader, payload []byte) bool {
+		if header.Icon == IconDead {
+			return false
+		}
+
+		for _, name := range keepNames {
+			if uncompressedChunkName(header.Name) == name {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+func findChunkPayload(chunks []Chunk, name raff.FourOctets) []byte {
+	for _, chunk := range chunks {
+		if chunk.Name == name {
+			return chunk.Payload
+		}
+	}
+
+	return nil
+}
+
+// MergeDebugInfo is the inverse of Strip. It returns a copy of release with the optional chunks of
+// debug that release does not have, such as bld0 and src0. Both packs must have the same type info,
+// constant memory or dmx1 reference and ledger, otherwise ErrDebugInfoMismatch is returned. The chunks of release are
+// kept as stored, the result is in canonical chunk order, and signatures and padding are left out.
+func MergeDebugInfo(release []byte, debug []byte) ([]byte, error) {
+	for _, data := range [][]byte{release, debug} {
+		if err := Verify(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	_, releaseLogical, releaseErr := logicalChunks(release)
+	if releaseErr != nil {
+		return nil, fmt.Errorf("merge debug info release %w", releaseErr)
+	}
+
+	_, debugLogical, debugErr := logicalChunks(debug)
+	if debugErr != nil {
+		return nil, fmt.Errorf("merge debug info debug %w", debugErr)
+	}
+
+	for _, name := range append([]raff.FourOctets{externalConstantMemoryName}, mandatoryChunkNames...) {
+		if !bytes.Equal(findChunkPayload(releaseLogical, name), findChunkPayload(debugLogical, name)) {
+			return nil, fmt.Errorf("%w: '%v' differs", ErrDebugInfoMismatch, raff.NameToString(name))
+		}
+	}
+
+	_, flags, headerErr := readPackHeaderChunk(newLimitedChunkReader(bytes.NewReader(release), Limits{}))
+	if headerErr != nil {
+		return nil, headerErr
+	}
+
+	packHeader, releaseStored, storedErr := storedChunks(release)
+	if storedErr != nil {
+		return nil, fmt.Errorf("merge debug info release %w", storedErr)
+	}
+
+	releaseNames := make(map[raff.FourOctets]bool)
+	chunks := make([]Chunk, 0, len(releaseStored)+len(debugLogical))
+
+	for _, chunk := range releaseStored {
+		if chunk.Icon == IconDead || chunk.Name == paddingName {
+			continue
+		}
+
+		releaseNames[uncompressedChunkName(chunk.Name)] = true
+		chunks = append(chunks, chunk)
+	}
+
+	for _, chunk := range debugLogical {
+		if releaseNames[chunk.Name] || isMandatoryChunk(chunk.Name) {
+			continue
+		}
+
+		switch chunk.Name {
+		case paddingName, signatureName, chunkIndexName, externalConstantMemoryName:
+			continue
+		}
+
+		if flags&packFlagChunkChecksums != 0 {
+			sealed := make([]byte, len(chunk.Payload)+chunkChecksumOctetCount)
+			sealChunkPayload(sealed, chunk.Payload)
+			chunk.Payload = sealed
+		}
+
+		chunks = append(chunks, chunk)
+	}
+
+	sort.SliceStable(chunks, func(a, b int) bool {
+		return isCanonicalChunkOrder(chunks[a], chunks[b])
+	})
+
+	return writeStoredChunks(packHeader, chunks)
+}
@@ -0,0 +1,98 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func developmentOptions() Options {
+	return Options{
+		BuildMetadata: &BuildMetadata{Timestamp: time.Unix(1700000000, 0).UTC(), ToolVersion: "swamp 0.9.1"},
+		SourceFiles:   []string{"main.swamp", "lib/math.swamp"},
+		Metadata:      map[string]string{"commit": "3eb4de7"},
+	}
+}
+
+func TestStrip(t *testing.T) {
+	for _, options := range []Options{developmentOptions(), {ChunkChecksums: true, SourceFiles: []string{"main.swamp"}}} {
+		data := packSample(t, options)
+
+		stripped, err := Strip(data)
+		if err != nil {
+			t.Fatalf("strip: %v", err)
+		}
+
+		if len(stripped) >= len(data) {
+			t.Errorf("stripped pack is %d octets, original is %d", len(stripped), len(data))
+		}
+
+		contents := unpackData(t, stripped)
+		checkSampleContents(t, contents)
+
+		if contents.BuildMetadata != nil || contents.SourceFiles != nil || contents.Metadata != nil {
+			t.Errorf("stripped pack still has debug information: %+v", contents)
+		}
+	}
+}
+
+func TestStripKeep(t *testing.T) {
+	stripped, err := Strip(packSample(t, developmentOptions()), metadataName)
+	if err != nil {
+		t.Fatalf("strip: %v", err)
+	}
+
+	contents := unpackData(t, stripped)
+	if contents.Metadata["commit"] != "3eb4de7" {
+		t.Errorf("kept metadata is %v", contents.Metadata)
+	}
+
+	if contents.BuildMetadata != nil || contents.SourceFiles != nil {
+		t.Errorf("stripped pack still has build metadata or source files: %+v", contents)
+	}
+}
+
+func TestMergeDebugInfoRoundTrip(t *testing.T) {
+	checksummed := developmentOptions()
+	checksummed.ChunkChecksums = true
+
+	for _, options := range []Options{developmentOptions(), checksummed} {
+		debug := packSample(t, options)
+
+		release, stripErr := Strip(debug)
+		if stripErr != nil {
+			t.Fatalf("strip: %v", stripErr)
+		}
+
+		merged, mergeErr := MergeDebugInfo(release, debug)
+		if mergeErr != nil {
+			t.Fatalf("merge debug info: %v", mergeErr)
+		}
+
+		if !bytes.Equal(merged, debug) {
+			t.Errorf("merged pack differs from the original with %+v", options)
+		}
+	}
+}
+
+func TestMergeDebugInfoMismatch(t *testing.T) {
+	release, stripErr := Strip(packSample(t, developmentOptions()))
+	if stripErr != nil {
+		t.Fatalf("strip: %v", stripErr)
+	}
+
+	otherDebug, packErr := PackWithOptions([]byte{0x09}, sampleConstantMemory, sampleTypeInfo, developmentOptions())
+	if packErr != nil {
+		t.Fatalf("pack: %v", packErr)
+	}
+
+	if _, err := MergeDebugInfo(release, otherDebug); !errors.Is(err, ErrDebugInfoMismatch) {
+		t.Fatalf("expected ErrDebugInfoMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+// IconDead replaces the icon of a chunk that has been marked dead. Readers skip dead chunks.
+const IconDead raff.FourOctets = 0xF09F9280 // 💀
+
+var ErrChunkNotFound = errors.New("chunk not found")
+
+// ReadWriterAt is a pack file that can be patched in place, such as an *os.File.
+type ReadWriterAt interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// MarkDead overwrites the icon of the first live chunk called name with IconDead, without
+// rewriting the rest of the file. A replacement chunk can then be appended to the file.
+func MarkDead(file ReadWriterAt, name raff.FourOctets) error {
+	reader := io.NewSectionReader(file, 0, math.MaxInt64)
+	if err := readFileHeader(reader); err != nil {
+		return err
+	}
+
+	offset := int64(len(raff.FileHeader()))
+	isPackHeader := true
+
+	for {
+		header, headerErr := readFullChunkHeader(io.NewSectionReader(file, offset, chunkHeaderOctetCount))
+		if errors.Is(headerErr, io.EOF) {
+			return fmt.Errorf("%w: '%v'", ErrChunkNotFound, raff.NameToString(name))
+		}
+
+		if headerErr != nil {
+			return fmt.Errorf("mark dead read chunk %w", headerErr)
+		}
+
+		if !isPackHeader && header.Name == name && header.Icon != IconDead {
+			var icon [4]byte
+			binary.BigEndian.PutUint32(icon[:], uint32(IconDead))
+			if _, err := file.WriteAt(icon[:], offset); err != nil {
+				return fmt.Errorf("mark dead write icon %w", err)
+			}
+
+			return nil
+		}
+
+		isPackHeader = false
+		offset += chunkHeaderOctetCount + int64(header.OctetCount)
+	}
+}
+
+// Compact returns a copy of the pack with all dead chunks removed. A signature only survives if there
+// was nothing to remove.
+func Compact(data []byte) ([]byte, error) {
+	return rewriteChunks(data, func(header raff.ChunkHeader, payload []byte) bool {
+		return header.Icon != IconDead
+	})
+}
@@ -0,0 +1,120 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"io"
+	"testing"
+)
+
+// memoryFile is a ReadWriterAt over a slice, standing in for an *os.File.
+type memoryFile struct {
+	octets []byte
+}
+
+func (f *memoryFile) ReadAt(target []byte, offset int64) (int, error) {
+	if offset >= int64(len(f.octets)) {
+		return 0, io.EOF
+	}
+
+	octetCount := copy(target, f.octets[offset:])
+	if octetCount < len(target) {
+		return octetCount, io.EOF
+	}
+
+	return octetCount, nil
+}
+
+func (f *memoryFile) WriteAt(octets []byte, offset int64) (int, error) {
+	if end := offset + int64(len(octets)); end > int64(len(f.octets)) {
+		f.octets = append(f.octets, make([]byte, end-int64(len(f.octets)))...)
+	}
+
+	return copy(f.octets[offset:], octets), nil
+}
+
+func TestMarkDeadAndReplace(t *testing.T) {
+	file := &memoryFile{octets: packSample(t, Options{Metadata: map[string]string{"commit": "old"}})}
+
+	if err := MarkDead(file, metadataName); err != nil {
+		t.Fatalf("mark dead: %v", err)
+	}
+
+	if contents := unpackData(t, file.octets); contents.Metadata != nil {
+		t.Errorf("dead metadata was unpacked: %v", contents.Metadata)
+	}
+
+	payload, payloadErr := metadataPayload(map[string]string{"commit": "new"})
+	if payloadErr != nil {
+		t.Fatalf("metadata payload: %v", payloadErr)
+	}
+
+	var replacement bytes.Buffer
+	if err := writeChunkHeader(&replacement, IconMetadata, metadataName, payload); err != nil {
+		t.Fatalf("write chunk: %v", err)
+	}
+
+	file.WriteAt(replacement.Bytes(), int64(len(file.octets)))
+
+	contents := unpackData(t, file.octets)
+	checkSampleContents(t, contents)
+
+	if contents.Metadata["commit"] != "new" {
+		t.Errorf("metadata is %v, expected the replacement", contents.Metadata)
+	}
+
+	compacted, compactErr := Compact(file.octets)
+	if compactErr != nil {
+		t.Fatalf("compact: %v", compactErr)
+	}
+
+	expected := packSample(t, Options{Metadata: map[string]string{"commit": "new"}})
+	if !bytes.Equal(compacted, expected) {
+		t.Errorf("compacted pack differs from a pack written with the replacement")
+	}
+
+	if err := MarkDead(&memoryFile{octets: compacted}, buildMetadataName); !errors.Is(err, ErrChunkNotFound) {
+		t.Errorf("expected ErrChunkNotFound, got %v", err)
+	}
+}
+
+func TestCompactSignedPack(t *testing.T) {
+	privateKey := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x42}, ed25519.SeedSize))
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+
+	file := &memoryFile{octets: packSample(t, Options{Metadata: map[string]string{"commit": "old"}})}
+	if err := MarkDead(file, metadataName); err != nil {
+		t.Fatalf("mark dead: %v", err)
+	}
+
+	signed, signErr := Sign(file.octets, privateKey)
+	if signErr != nil {
+		t.Fatalf("sign: %v", signErr)
+	}
+
+	compacted, compactErr := Compact(signed)
+	if compactErr != nil {
+		t.Fatalf("compact: %v", compactErr)
+	}
+
+	if err := VerifySignature(compacted, publicKey); !errors.Is(err, ErrNotSigned) {
+		t.Errorf("expected the stale signature to be dropped, got %v", err)
+	}
+
+	alreadyCompact, _ := signedSample(t)
+
+	unchanged, unchangedErr := Compact(alreadyCompact)
+	if unchangedErr != nil {
+		t.Fatalf("compact: %v", unchangedErr)
+	}
+
+	if err := VerifySignature(unchanged, publicKey); err != nil {
+		t.Errorf("a pack without dead chunks should keep its signature: %v", err)
+	}
+}
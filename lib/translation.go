@@ -0,0 +1,87 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+const IconTranslation raff.FourOctets = 0xF09F8C90 // 🌐
+
+var translationName = raff.MakeFourOctets('i', '1', '8', 'n')
+
+// translationChunks returns one i18n chunk per language, sorted by language.
+func translationChunks(tables map[string]map[string]string) ([]Chunk, error) {
+	languages := make([]string, 0, len(tables))
+	for language := range tables {
+		languages = append(languages, language)
+	}
+
+	sort.Strings(languages)
+
+	chunks := make([]Chunk, 0, len(languages))
+
+	for _, language := range languages {
+		var payload bytes.Buffer
+		if err := writeString(&payload, language); err != nil {
+			return nil, fmt.Errorf("translation language %w", err)
+		}
+
+		entries, entriesErr := metadataPayload(tables[language])
+		if entriesErr != nil {
+			return nil, fmt.Errorf("translation '%v' %w", language, entriesErr)
+		}
+
+		payload.Write(entries)
+
+		chunks = append(chunks, Chunk{Icon: IconTranslation, Name: translationName, Payload: payload.Bytes()})
+	}
+
+	return chunks, nil
+}
+
+func readTranslation(payload []byte) (string, map[string]string, error) {
+	reader := bytes.NewReader(payload)
+
+	language, languageErr := readString(reader)
+	if languageErr != nil {
+		return "", nil, fmt.Errorf("translation language %w", languageErr)
+	}
+
+	entries, entriesErr := readMetadata(payload[len(payload)-reader.Len():])
+	if entriesErr != nil {
+		return "", nil, fmt.Errorf("translation '%v' %w", language, entriesErr)
+	}
+
+	return language, entries, nil
+}
+
+// StripTranslations returns a copy of the pack with the i18n chunks of every language not in keep removed.
+// A signature only survives if no chunk was removed.
+func StripTranslations(data []byte, keep ...string) ([]byte, error) {
+	return rewriteChunks(data, func(header raff.ChunkHeader, payload []byte) bool {
+		if header.Name != translationName || header.Icon == IconDead {
+			return true
+		}
+
+		language, languageErr := readString(bytes.NewReader(payload))
+		if languageErr != nil {
+			return false
+		}
+
+		for _, keptLanguage := range keep {
+			if language == keptLanguage {
+				return true
+			}
+		}
+
+		return false
+	})
+}
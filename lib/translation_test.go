@@ -0,0 +1,76 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func sampleTranslations() map[string]map[string]string {
+	return map[string]map[string]string{
+		"en": {"menu.start": "Start", "menu.quit": "Quit", "menu.options": "Options"},
+		"sv": {"menu.start": "Starta", "menu.quit": "Avsluta"},
+	}
+}
+
+func TestTranslationsRoundTrip(t *testing.T) {
+	translations := sampleTranslations()
+
+	for _, options := range []Options{{Translations: translations}, {Translations: translations, ChunkChecksums: true}} {
+		data := packSample(t, options)
+
+		contents := unpackData(t, data)
+		checkSampleContents(t, contents)
+
+		if !reflect.DeepEqual(contents.Translations, translations) {
+			t.Errorf("translations are %v, expected %v", contents.Translations, translations)
+		}
+
+		if err := Verify(bytes.NewReader(data)); err != nil {
+			t.Errorf("verify: %v", err)
+		}
+
+		if err := VerifyStream(bytes.NewReader(data), DefaultLimits()); err != nil {
+			t.Errorf("verify stream: %v", err)
+		}
+	}
+}
+
+func TestStripTranslations(t *testing.T) {
+	for _, options := range []Options{{Translations: sampleTranslations()}, {Translations: sampleTranslations(), ChunkChecksums: true}} {
+		stripped, err := StripTranslations(packSample(t, options), "sv")
+		if err != nil {
+			t.Fatalf("strip translations: %v", err)
+		}
+
+		contents := unpackData(t, stripped)
+		if len(contents.Translations) != 1 || contents.Translations["sv"]["menu.start"] != "Starta" {
+			t.Errorf("expected only the sv translation, got %v", contents.Translations)
+		}
+	}
+}
+
+func TestStripTranslationsSignedPack(t *testing.T) {
+	privateKey := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x42}, ed25519.SeedSize))
+
+	signed, signErr := Sign(packSample(t, Options{Translations: sampleTranslations()}), privateKey)
+	if signErr != nil {
+		t.Fatalf("sign: %v", signErr)
+	}
+
+	stripped, stripErr := StripTranslations(signed, "sv")
+	if stripErr != nil {
+		t.Fatalf("strip translations: %v", stripErr)
+	}
+
+	if err := VerifySignature(stripped, privateKey.Public().(ed25519.PublicKey)); !errors.Is(err, ErrNotSigned) {
+		t.Errorf("expected the stale signature to be dropped, got %v", err)
+	}
+}
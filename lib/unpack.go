@@ -0,0 +1,513 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+var (
+	ErrIconMismatch       = errors.New("chunk icon does not match its name")
+	ErrUnsupportedVersion = errors.New("unsupported pack version")
+	ErrMissingChunk       = errors.New("missing chunk")
+	ErrDuplicateChunk     = errors.New("duplicate chunk")
+	ErrChunkOutOfOrder    = errors.New("chunk out of order")
+	ErrDisallowedChunk    = errors.New("chunk not allowed")
+)
+
+// mandatoryChunkNames are the chunks every pack has, in canonical order.
+var mandatoryChunkNames = []raff.FourOctets{typeInfoName, constantMemoryName, ledgerName}
+
+// Contents holds the chunk payloads of an unpacked pack.
+type Contents struct {
+	TypeInfo       []byte
+	ConstantMemory []byte
+	Ledger         []byte
+	BuildMetadata  *BuildMetadata
+	InstructionSet *InstructionSet
+	SourceFiles    []string
+	SourceHash     *[32]byte
+	Metadata       map[string]string
+
+	// Translations holds the i18n chunks by language.
+	Translations map[string]map[string]string
+
+	// CustomChunks are the chunks not known by this package, in the order they were found.
+	CustomChunks []Chunk
+
+	// ConstantMemorySkipped is set when SkipConstantMemory read past the constant memory chunk,
+	// to tell a skipped constant memory from a missing one.
+	ConstantMemorySkipped bool
+}
+
+// UnpackOptions changes how UnpackWithOptions reads a pack. The zero value reads like Unpack.
+type UnpackOptions struct {
+	Limits Limits
+
+	// RequireCanonicalOrder rejects packs whose first chunks are not sti0, dme1 and ldg0, in that order.
+	RequireCanonicalOrder bool
+
+	// PublicKey, when set, requires the pack to be signed by the matching private key. The whole pack
+	// is read into memory before it is checked.
+	PublicKey ed25519.PublicKey
+
+	// AllowedChunks, when not nil, rejects packs with chunks other than sti0, dme1, ldg0 and the
+	// chunks listed, e.g. to only accept plain packs in a sandbox. A compressed chunk is allowed if its
+	// uncompressed name is. Dead chunks are ignored.
+	AllowedChunks []raff.FourOctets
+
+	// SkipConstantMemory reads past the constant memory chunk without keeping, decompressing or
+	// checksumming it, for tools that only look at the type info and the ledger. Contents.ConstantMemory is nil
+	// and Contents.ConstantMemorySkipped is set.
+	SkipConstantMemory bool
+}
+
+// eagerPayloadOctetCount is the largest payload that is allocated before it is read. Larger payloads
+// grow as octets arrive, so a forged chunk length can not allocate more than the data holds.
+const eagerPayloadOctetCount = 64 * 1024
+
+func readChunkPayload(reader io.Reader, header raff.ChunkHeader) ([]byte, error) {
+	if header.OctetCount <= eagerPayloadOctetCount {
+		payload := make([]byte, header.OctetCount)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+
+			return nil, fmt.Errorf("chunk '%v' %w", raff.NameToString(header.Name), err)
+		}
+
+		return payload, nil
+	}
+
+	var payload bytes.Buffer
+	if _, err := io.CopyN(&payload, reader, int64(header.OctetCount)); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return nil, fmt.Errorf("chunk '%v' %w", raff.NameToString(header.Name), err)
+	}
+
+	return payload.Bytes(), nil
+}
+
+func checkIcon(header raff.ChunkHeader, expectedIcon raff.FourOctets) error {
+	if header.Icon != expectedIcon {
+		return fmt.Errorf("%w: '%v'", ErrIconMismatch, raff.NameToString(header.Name))
+	}
+
+	return nil
+}
+
+func isMandatoryChunk(name raff.FourOctets) bool {
+	for _, mandatoryName := range mandatoryChunkNames {
+		if uncompressedChunkName(name) == mandatoryName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isAllowedChunk(name raff.FourOctets, allowed []raff.FourOctets) bool {
+	if isMandatoryChunk(name) {
+		return true
+	}
+
+	name = uncompressedChunkName(name)
+
+	for _, allowedName := range allowed {
+		if name == uncompressedChunkName(allowedName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func readPackHeader(reader *limitedChunkReader) (uint32, error) {
+	version, flags, headerErr := readPackHeaderChunk(reader)
+	if headerErr != nil {
+		return 0, headerErr
+	}
+
+	if version.Major != CurrentVersion.Major {
+		return 0, fmt.Errorf("%w: %v", ErrUnsupportedVersion, version)
+	}
+
+	// A newer minor version may define flags that older readers can ignore. Within the versions this
+	// package knows, an unknown flag means the pack is malformed.
+	if version.Minor <= CurrentVersion.Minor && flags&^packFlagChunkChecksums != 0 {
+		return 0, fmt.Errorf("%w: %v has unknown pack flags %08X", ErrUnsupportedVersion, version, flags)
+	}
+
+	return flags, nil
+}
+
+// knownChunkIcons maps every chunk name this package reads to the icon it must have.
+var knownChunkIcons = map[raff.FourOctets]raff.FourOctets{
+	typeInfoName:                 IconTypeInfo,
+	constantMemoryName:           IconConstantMemory,
+	ledgerName:                   IconLedger,
+	compressedConstantMemoryName: IconConstantMemory,
+	compressedLedgerName:         IconLedger,
+	paddingName:                  IconPadding,
+	buildMetadataName:            IconBuildMetadata,
+	sourceFilesName:              IconSourceFiles,
+	sourceHashName:               IconSourceHash,
+	signatureName:                IconSignature,
+	metadataName:                 IconMetadata,
+	translationName:              IconTranslation,
+	chunkIndexName:               IconChunkIndex,
+	externalConstantMemoryName:   IconExternalConstantMemory,
+	instructionSetName:           IconInstructionSet,
+}
+
+func assignPayload(target *[]byte, header raff.ChunkHeader, payload []byte) error {
+	if *target != nil {
+		return fmt.Errorf("%w: '%v'", ErrDuplicateChunk, raff.NameToString(header.Name))
+	}
+
+	*target = payload
+
+	return nil
+}
+
+func assignBuildMetadata(contents *Contents, header raff.ChunkHeader, payload []byte) error {
+	if contents.BuildMetadata != nil {
+		return fmt.Errorf("%w: '%v'", ErrDuplicateChunk, raff.NameToString(header.Name))
+	}
+
+	meta, metaErr := readBuildMetadata(payload)
+	if metaErr != nil {
+		return metaErr
+	}
+
+	contents.BuildMetadata = meta
+
+	return nil
+}
+
+func assignInstructionSet(contents *Contents, header raff.ChunkHeader, payload []byte) error {
+	if contents.InstructionSet != nil {
+		return fmt.Errorf("%w: '%v'", ErrDuplicateChunk, raff.NameToString(header.Name))
+	}
+
+	set, setErr := readInstructionSet(payload)
+	if setErr != nil {
+		return setErr
+	}
+
+	contents.InstructionSet = set
+
+	return nil
+}
+
+func assignSourceFiles(contents *Contents, header raff.ChunkHeader, payload []byte) error {
+	if contents.SourceFiles != nil {
+		return fmt.Errorf("%w: '%v'", ErrDuplicateChunk, raff.NameToString(header.Name))
+	}
+
+	paths, pathsErr := readSourceFiles(payload)
+	if pathsErr != nil {
+		return pathsErr
+	}
+
+	contents.SourceFiles = paths
+
+	return nil
+}
+
+func assignSourceHash(contents *Contents, header raff.ChunkHeader, payload []byte) error {
+	if contents.SourceHash != nil {
+		return fmt.Errorf("%w: '%v'", ErrDuplicateChunk, raff.NameToString(header.Name))
+	}
+
+	hash, hashErr := readSourceHash(payload)
+	if hashErr != nil {
+		return hashErr
+	}
+
+	contents.SourceHash = hash
+
+	return nil
+}
+
+func assignMetadata(contents *Contents, header raff.ChunkHeader, payload []byte) error {
+	if contents.Metadata != nil {
+		return fmt.Errorf("%w: '%v'", ErrDuplicateChunk, raff.NameToString(header.Name))
+	}
+
+	metadata, metadataErr := readMetadata(payload)
+	if metadataErr != nil {
+		return metadataErr
+	}
+
+	contents.Metadata = metadata
+
+	return nil
+}
+
+func assignTranslation(contents *Contents, payload []byte) error {
+	language, entries, translationErr := readTranslation(payload)
+	if translationErr != nil {
+		return translationErr
+	}
+
+	if _, wasFound := contents.Translations[language]; wasFound {
+		return fmt.Errorf("%w: '%v' for language '%v'", ErrDuplicateChunk, raff.NameToString(translationName), language)
+	}
+
+	if contents.Translations == nil {
+		contents.Translations = make(map[string]map[string]string)
+	}
+
+	contents.Translations[language] = entries
+
+	return nil
+}
+
+func assignChunk(contents *Contents, header raff.ChunkHeader, payload []byte) error {
+	switch header.Name {
+	case typeInfoName:
+		return assignPayload(&contents.TypeInfo, header, payload)
+	case constantMemoryName:
+		return assignPayload(&contents.ConstantMemory, header, payload)
+	case ledgerName:
+		return assignPayload(&contents.Ledger, header, payload)
+	case buildMetadataName:
+		return assignBuildMetadata(contents, header, payload)
+	case instructionSetName:
+		return assignInstructionSet(contents, header, payload)
+	case sourceFilesName:
+		return assignSourceFiles(contents, header, payload)
+	case sourceHashName:
+		return assignSourceHash(contents, header, payload)
+	case metadataName:
+		return assignMetadata(contents, header, payload)
+	case translationName:
+		return assignTranslation(contents, payload)
+	case paddingName, signatureName, externalConstantMemoryName, chunkIndexName:
+		return nil
+	default:
+		contents.CustomChunks = append(contents.CustomChunks, Chunk{Icon: header.Icon, Name: header.Name, Payload: payload})
+		return nil
+	}
+}
+
+// unpack passes every problem it finds to report. Problems that leave the chunk framing intact only
+// stop the reading if report returns false. The returned contents are incomplete if anything was reported.
+func unpack(reader io.Reader, options UnpackOptions, report func(err error) bool) *Contents {
+	chunkReader := newLimitedChunkReader(reader, options.Limits)
+
+	_, flagsErr := readPackHeader(chunkReader)
+	if flagsErr != nil {
+		report(flagsErr)
+		return nil
+	}
+
+	contents := &Contents{}
+	liveChunkIndex := 0
+	wasConstantMemorySkipped := false
+	isConstantMemoryExternal := false
+
+	for {
+		header, headerErr := chunkReader.readChunkHeader()
+		if errors.Is(headerErr, io.EOF) {
+			break
+		}
+
+		if headerErr != nil {
+			report(fmt.Errorf("unpack read chunk %w", headerErr))
+			return nil
+		}
+
+		isSkipped := options.SkipConstantMemory && header.Icon != IconDead &&
+			uncompressedChunkName(header.Name) == constantMemoryName
+
+		var (
+			payload []byte
+			readErr error
+		)
+
+		if isSkipped {
+			readErr = chunkReader.skipChunkPayload(header)
+		} else {
+			payload, readErr = chunkReader.readChunkPayload(header)
+		}
+
+		if readErr != nil {
+			report(fmt.Errorf("unpack read chunk %w", readErr))
+			return nil
+		}
+
+		if header.Icon == IconDead {
+			continue
+		}
+
+		if options.AllowedChunks != nil && !isAllowedChunk(header.Name, options.AllowedChunks) {
+			if !report(fmt.Errorf("%w: '%v'", ErrDisallowedChunk, raff.NameToString(header.Name))) {
+				return nil
+			}
+
+			continue
+		}
+
+		if !isSkipped {
+			checkedPayload, checksumErr := chunkReader.checkedPayload(header, payload)
+			if checksumErr != nil && !report(checksumErr) {
+				return nil
+			}
+
+			payload = checkedPayload
+		}
+
+		if options.RequireCanonicalOrder && liveChunkIndex < len(mandatoryChunkNames) &&
+			referencedChunkName(header.Name) != mandatoryChunkNames[liveChunkIndex] {
+			if !report(fmt.Errorf("%w: found '%v' where '%v' was expected", ErrChunkOutOfOrder,
+				raff.NameToString(header.Name), raff.NameToString(mandatoryChunkNames[liveChunkIndex]))) {
+				return nil
+			}
+		}
+
+		liveChunkIndex++
+
+		if expectedIcon, isKnown := knownChunkIcons[header.Name]; isKnown {
+			if err := checkIcon(header, expectedIcon); err != nil && !report(err) {
+				return nil
+			}
+		}
+
+		if isSkipped {
+			if wasConstantMemorySkipped &&
+				!report(fmt.Errorf("%w: '%v'", ErrDuplicateChunk, raff.NameToString(constantMemoryName))) {
+				return nil
+			}
+
+			wasConstantMemorySkipped = true
+			contents.ConstantMemorySkipped = true
+
+			continue
+		}
+
+		if uncompressedName, isCompressed := compressedChunkNames[header.Name]; isCompressed {
+			inflated, inflateErr := decompressChunkPayload(header, payload, chunkReader.limits.MaxChunkOctetCount)
+			if inflateErr != nil {
+				if !report(inflateErr) {
+					return nil
+				}
+
+				continue
+			}
+
+			header.Name = uncompressedName
+			payload = inflated
+		}
+
+		if header.Name == externalConstantMemoryName {
+			isConstantMemoryExternal = true
+		}
+
+		if err := assignChunk(contents, header, payload); err != nil && !report(err) {
+			return nil
+		}
+	}
+
+	if contents.TypeInfo == nil && !report(fmt.Errorf("%w: '%v'", ErrMissingChunk, raff.NameToString(typeInfoName))) {
+		return nil
+	}
+
+	if contents.ConstantMemory == nil && !wasConstantMemorySkipped && !isConstantMemoryExternal &&
+		!report(fmt.Errorf("%w: '%v'", ErrMissingChunk, raff.NameToString(constantMemoryName))) {
+		return nil
+	}
+
+	if contents.Ledger == nil && !report(fmt.Errorf("%w: '%v'", ErrMissingChunk, raff.NameToString(ledgerName))) {
+		return nil
+	}
+
+	return contents
+}
+
+// Unpack reads a pack and returns its chunk payloads. The main pack written by PackSplit has a dmx1
+// chunk in place of dme1, so it unpacks with ConstantMemory nil; UnpackSplit loads it with its constant memory.
+func Unpack(reader io.Reader) (*Contents, error) {
+	return UnpackWithOptions(reader, UnpackOptions{})
+}
+
+func readSigned(reader io.Reader, options UnpackOptions) (io.Reader, error) {
+	maxTotalOctetCount := options.Limits.withDefaults().MaxTotalOctetCount
+
+	data, readErr := io.ReadAll(io.LimitReader(reader, maxTotalOctetCount+1))
+	if readErr != nil {
+		return nil, fmt.Errorf("unpack read signed pack %w", readErr)
+	}
+
+	if int64(len(data)) > maxTotalOctetCount {
+		return nil, fmt.Errorf("%w: more than %d octets", ErrPackTooLarge, maxTotalOctetCount)
+	}
+
+	if err := verifySignature(data, options.PublicKey, options.Limits); err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(data), nil
+}
+
+func UnpackWithOptions(reader io.Reader, options UnpackOptions) (*Contents, error) {
+	if options.PublicKey != nil {
+		signedReader, signedErr := readSigned(reader, options)
+		if signedErr != nil {
+			return nil, signedErr
+		}
+
+		reader = signedReader
+	}
+
+	var firstErr error
+
+	contents := unpack(reader, options, func(err error) bool {
+		firstErr = err
+		return false
+	})
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return contents, nil
+}
+
+// Verify checks that a pack is well formed without keeping its contents.
+func Verify(reader io.Reader) error {
+	return VerifyWithOptions(reader, UnpackOptions{})
+}
+
+func VerifyWithOptions(reader io.Reader, options UnpackOptions) error {
+	_, err := UnpackWithOptions(reader, options)
+
+	return err
+}
+
+// VerifyAll is like Verify, but instead of stopping at the first problem it collects every problem
+// it can find. Only problems that break the chunk framing, such as a truncated chunk, stop it early.
+func VerifyAll(data []byte) []error {
+	var errs []error
+
+	unpack(bytes.NewReader(data), UnpackOptions{}, func(err error) bool {
+		errs = append(errs, err)
+		return true
+	})
+
+	return errs
+}
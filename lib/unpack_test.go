@@ -0,0 +1,273 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+func TestUnpackShortReads(t *testing.T) {
+	data := packSample(t, Options{ChunkChecksums: true, BlockAlign: 64, ChunkIndex: true})
+
+	for _, reader := range []io.Reader{
+		iotest.OneByteReader(bytes.NewReader(data)),
+		iotest.HalfReader(bytes.NewReader(data)),
+		iotest.DataErrReader(bytes.NewReader(data)),
+	} {
+		contents, err := Unpack(reader)
+		if err != nil {
+			t.Fatalf("unpack: %v", err)
+		}
+
+		checkSampleContents(t, contents)
+	}
+}
+
+func TestUnpackTruncated(t *testing.T) {
+	data := packSample(t, Options{})
+
+	_, err := Unpack(iotest.OneByteReader(bytes.NewReader(data[:len(data)-1])))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected io.ErrUnexpectedEOF for a truncated pack, got %v", err)
+	}
+}
+
+func TestTrailingPartialChunkHeader(t *testing.T) {
+	data := packSample(t, Options{})
+
+	for _, octetCount := range []int{1, 4, 8, chunkHeaderOctetCount - 1} {
+		truncated := append(append([]byte{}, data...), bytes.Repeat([]byte{0xee}, octetCount)...)
+
+		if _, err := Unpack(bytes.NewReader(truncated)); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("unpack with %d trailing octets: expected io.ErrUnexpectedEOF, got %v", octetCount, err)
+		}
+
+		if err := Verify(bytes.NewReader(truncated)); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("verify with %d trailing octets: expected io.ErrUnexpectedEOF, got %v", octetCount, err)
+		}
+
+		if err := VerifyStream(bytes.NewReader(truncated), DefaultLimits()); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("verify stream with %d trailing octets: expected io.ErrUnexpectedEOF, got %v", octetCount, err)
+		}
+
+		if errs := VerifyAll(truncated); len(errs) == 0 || !errors.Is(errs[len(errs)-1], io.ErrUnexpectedEOF) {
+			t.Errorf("verify all with %d trailing octets: expected io.ErrUnexpectedEOF, got %v", octetCount, errs)
+		}
+	}
+}
+
+func signedSample(t *testing.T) ([]byte, ed25519.PublicKey) {
+	t.Helper()
+
+	privateKey := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{0x42}, ed25519.SeedSize))
+
+	signed, err := Sign(packSample(t, Options{}), privateKey)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	return signed, privateKey.Public().(ed25519.PublicKey)
+}
+
+func TestAllowedChunksRejectsSignature(t *testing.T) {
+	signed, _ := signedSample(t)
+	options := UnpackOptions{AllowedChunks: []raff.FourOctets{}}
+
+	if _, err := UnpackWithOptions(bytes.NewReader(signed), options); !errors.Is(err, ErrDisallowedChunk) {
+		t.Fatalf("unpack: expected ErrDisallowedChunk, got %v", err)
+	}
+
+	if err := VerifyWithOptions(bytes.NewReader(signed), options); !errors.Is(err, ErrDisallowedChunk) {
+		t.Fatalf("verify: expected ErrDisallowedChunk, got %v", err)
+	}
+}
+
+func TestAllowedChunksAcceptsAllowListed(t *testing.T) {
+	signed, publicKey := signedSample(t)
+
+	for _, options := range []UnpackOptions{
+		{},
+		{AllowedChunks: []raff.FourOctets{signatureName}},
+		{AllowedChunks: []raff.FourOctets{signatureName}, PublicKey: publicKey},
+	} {
+		contents, err := UnpackWithOptions(bytes.NewReader(signed), options)
+		if err != nil {
+			t.Fatalf("unpack: %v", err)
+		}
+
+		checkSampleContents(t, contents)
+	}
+}
+
+func TestAllowedChunksCompressed(t *testing.T) {
+	data := packSample(t, Options{LedgerCompression: CompressionZlib, Metadata: map[string]string{"k": "v"}})
+
+	if _, err := UnpackWithOptions(bytes.NewReader(data), UnpackOptions{
+		AllowedChunks: []raff.FourOctets{metadataName},
+	}); err != nil {
+		t.Fatalf("unpack: %v", err)
+	}
+
+	if _, err := UnpackWithOptions(bytes.NewReader(data), UnpackOptions{
+		AllowedChunks: []raff.FourOctets{},
+	}); !errors.Is(err, ErrDisallowedChunk) {
+		t.Fatalf("expected ErrDisallowedChunk for kvs0, got %v", err)
+	}
+}
+
+func TestSkipConstantMemory(t *testing.T) {
+	for _, options := range []Options{{}, {ConstantMemoryCompression: CompressionZlib}, {ChunkChecksums: true}} {
+		data := packSample(t, options)
+
+		contents, err := UnpackWithOptions(bytes.NewReader(data), UnpackOptions{SkipConstantMemory: true})
+		if err != nil {
+			t.Fatalf("unpack: %v", err)
+		}
+
+		if contents.ConstantMemory != nil || !contents.ConstantMemorySkipped {
+			t.Errorf("constant memory was not skipped: %x", contents.ConstantMemory)
+		}
+
+		if !bytes.Equal(contents.Ledger, sampleLedger) || !bytes.Equal(contents.TypeInfo, sampleTypeInfo) {
+			t.Errorf("skipping the constant memory changed the other chunks: %+v", contents)
+		}
+	}
+
+	if unpackData(t, packSample(t, Options{})).ConstantMemorySkipped {
+		t.Error("ConstantMemorySkipped is set without SkipConstantMemory")
+	}
+}
+
+func TestSkipConstantMemoryIgnoresChecksum(t *testing.T) {
+	data := packSample(t, Options{ChunkChecksums: true})
+	data[bytes.Index(data, sampleConstantMemory)] ^= 0x01
+
+	if _, err := Unpack(bytes.NewReader(data)); !errors.Is(err, ErrChunkChecksum) {
+		t.Fatalf("expected ErrChunkChecksum without skipping, got %v", err)
+	}
+
+	contents, err := UnpackWithOptions(bytes.NewReader(data), UnpackOptions{SkipConstantMemory: true})
+	if err != nil {
+		t.Fatalf("unpack: %v", err)
+	}
+
+	if !contents.ConstantMemorySkipped || !bytes.Equal(contents.Ledger, sampleLedger) {
+		t.Errorf("unexpected contents %+v", contents)
+	}
+}
+
+func TestVerifyAllReportsEveryProblem(t *testing.T) {
+	options := Options{ChunkChecksums: true, Metadata: map[string]string{"k": "v"}}
+
+	packHeader, chunks, err := storedChunks(packSample(t, options))
+	if err != nil {
+		t.Fatalf("stored chunks: %v", err)
+	}
+
+	var broken []Chunk
+
+	for _, chunk := range chunks {
+		switch chunk.Name {
+		case typeInfoName:
+			chunk.Icon = IconLedger
+		case constantMemoryName:
+			chunk.Payload = append([]byte{}, chunk.Payload...)
+			chunk.Payload[0] ^= 0x01
+		case ledgerName:
+			continue
+		case metadataName:
+			broken = append(broken, chunk)
+		}
+
+		broken = append(broken, chunk)
+	}
+
+	data, writeErr := writeStoredChunks(packHeader, broken)
+	if writeErr != nil {
+		t.Fatalf("write stored chunks: %v", writeErr)
+	}
+
+	errs := VerifyAll(data)
+
+	for _, expected := range []error{ErrIconMismatch, ErrChunkChecksum, ErrDuplicateChunk, ErrMissingChunk} {
+		wasFound := false
+		for _, err := range errs {
+			wasFound = wasFound || errors.Is(err, expected)
+		}
+
+		if !wasFound {
+			t.Errorf("expected %v among %v", expected, errs)
+		}
+	}
+
+	if len(errs) != 4 {
+		t.Errorf("expected exactly four problems, got %v", errs)
+	}
+
+	if err := Verify(bytes.NewReader(data)); err == nil || err.Error() != errs[0].Error() {
+		t.Errorf("Verify should stop at the first problem %v, got %v", errs[0], err)
+	}
+}
+
+func TestTamperedIcon(t *testing.T) {
+	for _, options := range []Options{{}, {ChunkChecksums: true}} {
+		data := packSample(t, options)
+
+		// The icon of ldg0 is the four octets in front of its name.
+		ledgerHeader := bytes.Index(data, []byte("ldg0")) - 4
+		data[ledgerHeader+3] ^= 0x01
+
+		if _, err := Unpack(bytes.NewReader(data)); !errors.Is(err, ErrIconMismatch) {
+			t.Errorf("unpack: expected ErrIconMismatch, got %v", err)
+		}
+
+		if err := VerifyStream(bytes.NewReader(data), DefaultLimits()); !errors.Is(err, ErrIconMismatch) {
+			t.Errorf("verify stream: expected ErrIconMismatch, got %v", err)
+		}
+	}
+
+	data := packSample(t, Options{})
+	data[len(raff.FileHeader())+3] ^= 0x01
+
+	if _, err := Unpack(bytes.NewReader(data)); !errors.Is(err, ErrIconMismatch) {
+		t.Errorf("pack header: expected ErrIconMismatch, got %v", err)
+	}
+}
+
+func TestRequireCanonicalOrder(t *testing.T) {
+	options := UnpackOptions{RequireCanonicalOrder: true}
+
+	for _, packOptions := range []Options{{}, {LedgerCompression: CompressionZlib, Metadata: map[string]string{"k": "v"}}} {
+		if _, err := UnpackWithOptions(bytes.NewReader(packSample(t, packOptions)), options); err != nil {
+			t.Fatalf("unpack canonical pack: %v", err)
+		}
+	}
+
+	packHeader, chunks, err := storedChunks(packSample(t, Options{}))
+	if err != nil {
+		t.Fatalf("stored chunks: %v", err)
+	}
+
+	chunks[0], chunks[1] = chunks[1], chunks[0]
+
+	reordered, writeErr := writeStoredChunks(packHeader, chunks)
+	if writeErr != nil {
+		t.Fatalf("write stored chunks: %v", writeErr)
+	}
+
+	if _, err := UnpackWithOptions(bytes.NewReader(reordered), options); !errors.Is(err, ErrChunkOutOfOrder) {
+		t.Errorf("expected ErrChunkOutOfOrder, got %v", err)
+	}
+
+	checkSampleContents(t, unpackData(t, reordered))
+}
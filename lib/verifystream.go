@@ -0,0 +1,108 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+func verifyStreamChecksum(reader io.Reader, header raff.ChunkHeader) error {
+	if header.OctetCount < chunkChecksumOctetCount {
+		return fmt.Errorf("%w: '%v' is too short for a checksum", ErrChunkChecksum, raff.NameToString(header.Name))
+	}
+
+	hasher := crc32.NewIEEE()
+	if _, err := io.CopyN(hasher, reader, int64(header.OctetCount-chunkChecksumOctetCount)); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return fmt.Errorf("chunk '%v' %w", raff.NameToString(header.Name), err)
+	}
+
+	var checksum uint32
+	if err := binary.Read(reader, binary.BigEndian, &checksum); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return fmt.Errorf("chunk '%v' %w", raff.NameToString(header.Name), err)
+	}
+
+	if checksum != hasher.Sum32() {
+		return fmt.Errorf("%w: '%v'", ErrChunkChecksum, raff.NameToString(header.Name))
+	}
+
+	return nil
+}
+
+// VerifyStream checks the framing, icons, version, declared lengths and checksums of a pack
+// while reading it chunk by chunk. Payloads are streamed through and never kept, so memory
+// use does not grow with the size of the pack. It returns the same errors as Verify, except that
+// i18n chunks are not checked for a repeated language, since that is stored in the payload.
+func VerifyStream(reader io.Reader, limits Limits) error {
+	chunkReader := newLimitedChunkReader(reader, limits)
+
+	flags, flagsErr := readPackHeader(chunkReader)
+	if flagsErr != nil {
+		return flagsErr
+	}
+
+	foundChunks := make(map[raff.FourOctets]bool)
+
+	for {
+		header, headerErr := chunkReader.readChunkHeader()
+		if errors.Is(headerErr, io.EOF) {
+			break
+		}
+
+		if headerErr != nil {
+			return fmt.Errorf("verify stream read chunk %w", headerErr)
+		}
+
+		if flags&packFlagChunkChecksums != 0 {
+			if err := verifyStreamChecksum(chunkReader.reader, header); err != nil {
+				return err
+			}
+		} else if err := chunkReader.skipChunkPayload(header); err != nil {
+			return err
+		}
+
+		if header.Icon == IconDead {
+			continue
+		}
+
+		expectedIcon, isKnown := knownChunkIcons[header.Name]
+		if !isKnown {
+			continue
+		}
+
+		if err := checkIcon(header, expectedIcon); err != nil {
+			return err
+		}
+
+		name := uncompressedChunkName(header.Name)
+		if foundChunks[name] && name != paddingName && name != translationName {
+			return fmt.Errorf("%w: '%v'", ErrDuplicateChunk, raff.NameToString(name))
+		}
+
+		foundChunks[name] = true
+	}
+
+	for _, name := range mandatoryChunkNames {
+		if !foundChunks[name] && !(name == constantMemoryName && foundChunks[externalConstantMemoryName]) {
+			return fmt.Errorf("%w: '%v'", ErrMissingChunk, raff.NameToString(name))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+// Version is the pack format version. The major version is encoded as the last octet of the pack
+// header chunk name and the minor version in the pack header payload. Readers accept any minor
+// version of their major version and skip chunks they do not know.
+type Version struct {
+	Major uint8
+	Minor uint8
+}
+
+var CurrentVersion = Version{Major: 5, Minor: 0}
+
+var ErrNotASwampPack = errors.New("not a swamp pack")
+
+// maxPackHeaderPayloadOctetCount bounds what PeekVersion reads of the pack header payload.
+const maxPackHeaderPayloadOctetCount = 256
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+func packHeaderName(major uint8) raff.FourOctets {
+	return raff.MakeFourOctets('s', 'p', 'k', '0'+major)
+}
+
+func majorVersionFromPackHeaderName(name raff.FourOctets) (uint8, bool) {
+	digit := byte(name & 0xff)
+	if name&0xffffff00 != packHeaderName(0)&0xffffff00 || digit < '0' || digit > '9' {
+		return 0, false
+	}
+
+	return digit - '0', true
+}
+
+// packHeaderPayload is empty for minor version zero without flags, otherwise the minor version
+// octet followed by the flags. Newer minor versions may append more octets.
+func packHeaderPayload(version Version, flags uint32) []byte {
+	if version.Minor == 0 && flags == 0 {
+		return nil
+	}
+
+	payload := make([]byte, 5)
+	payload[0] = version.Minor
+	binary.BigEndian.PutUint32(payload[1:], flags)
+
+	return payload
+}
+
+func parsePackHeader(header raff.ChunkHeader, payload []byte) (Version, uint32, error) {
+	major, wasPackHeader := majorVersionFromPackHeaderName(header.Name)
+	if !wasPackHeader {
+		return Version{}, 0, ErrNotASwampPack
+	}
+
+	if header.Icon != IconPack {
+		return Version{}, 0, fmt.Errorf("%w: '%v'", ErrIconMismatch, raff.NameToString(header.Name))
+	}
+
+	if len(payload) == 0 {
+		return Version{Major: major}, 0, nil
+	}
+
+	if len(payload) < 5 {
+		return Version{}, 0, fmt.Errorf("pack header payload has unexpected octet count %d", len(payload))
+	}
+
+	return Version{Major: major, Minor: payload[0]}, binary.BigEndian.Uint32(payload[1:]), nil
+}
+
+func readPackHeaderChunk(reader *limitedChunkReader) (Version, uint32, error) {
+	if err := readFileHeader(reader.reader); err != nil {
+		return Version{}, 0, err
+	}
+
+	header, headerErr := readFullChunkHeader(reader.reader)
+	if headerErr != nil {
+		return Version{}, 0, fmt.Errorf("read pack header %w", headerErr)
+	}
+
+	if header.OctetCount > maxPackHeaderPayloadOctetCount {
+		return Version{}, 0, fmt.Errorf("%w: pack header declares %d octets", ErrChunkTooLarge, header.OctetCount)
+	}
+
+	payload, payloadErr := readChunkPayload(reader.reader, header)
+	if payloadErr != nil {
+		return Version{}, 0, fmt.Errorf("read pack header %w", payloadErr)
+	}
+
+	reader.chunkCount++
+	reader.totalOctetCount += chunkHeaderOctetCount + int64(header.OctetCount)
+
+	version, flags, parseErr := parsePackHeader(header, payload)
+	if parseErr != nil {
+		return Version{}, 0, parseErr
+	}
+
+	reader.flags = flags
+
+	return version, flags, nil
+}
+
+func readFileHeader(reader io.Reader) error {
+	fileHeader := make([]byte, len(raff.FileHeader()))
+	if _, err := io.ReadFull(reader, fileHeader); err != nil {
+		return fmt.Errorf("read header %w", err)
+	}
+
+	if !bytes.Equal(fileHeader, raff.FileHeader()) {
+		return ErrNotASwampPack
+	}
+
+	return nil
+}
+
+// PeekVersion reads only the RAFF file header and the pack header chunk and returns the pack
+// version. The rest of the reader is left unconsumed.
+func PeekVersion(reader io.Reader) (Version, error) {
+	version, _, err := readPackHeaderChunk(newLimitedChunkReader(reader, Limits{}))
+
+	return version, err
+}
@@ -0,0 +1,85 @@
+/*---------------------------------------------------------------------------------------------
+ *  Copyright (c) Peter Bjorklund. All rights reserved.
+ *  Licensed under the MIT License. See LICENSE in the project root for license information.
+ *--------------------------------------------------------------------------------------------*/
+
+package swamppack
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	raff "github.com/piot/raff-go/src"
+)
+
+// packWithHeader packs the sample and replaces its pack header chunk with one for version and flags.
+// An unknown chunk is appended, as a newer minor version could have written.
+func packWithHeader(t *testing.T, version Version, flags uint32) []byte {
+	t.Helper()
+
+	data := packSample(t, Options{})
+	chunks := data[len(raff.FileHeader())+chunkHeaderOctetCount:]
+
+	var buf bytes.Buffer
+	if err := raff.WriteHeader(&buf); err != nil {
+		t.Fatalf("write header: %v", err)
+	}
+
+	if err := writeChunkHeader(&buf, IconPack, packHeaderName(version.Major), packHeaderPayload(version, flags)); err != nil {
+		t.Fatalf("write pack header: %v", err)
+	}
+
+	buf.Write(chunks)
+
+	if err := writeChunkHeader(&buf, IconMetadata, raff.MakeFourOctets('n', 'e', 'w', '0'), []byte{0x01}); err != nil {
+		t.Fatalf("write unknown chunk: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestNewerMinorVersion(t *testing.T) {
+	version := Version{Major: CurrentVersion.Major, Minor: CurrentVersion.Minor + 1}
+	data := packWithHeader(t, version, 0)
+
+	peeked, peekErr := PeekVersion(bytes.NewReader(data))
+	if peekErr != nil {
+		t.Fatalf("peek version: %v", peekErr)
+	}
+
+	if peeked != version {
+		t.Errorf("peeked version %v, expected %v", peeked, version)
+	}
+
+	contents := unpackData(t, data)
+	checkSampleContents(t, contents)
+
+	if len(contents.CustomChunks) != 1 {
+		t.Errorf("expected the unknown chunk to be kept as a custom chunk, got %v", contents.CustomChunks)
+	}
+}
+
+func TestHigherMajorVersion(t *testing.T) {
+	data := packWithHeader(t, Version{Major: CurrentVersion.Major + 1}, 0)
+
+	if _, err := Unpack(bytes.NewReader(data)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+
+	if err := VerifyStream(bytes.NewReader(data), DefaultLimits()); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("verify stream: expected ErrUnsupportedVersion, got %v", err)
+	}
+}
+
+func TestUnknownPackFlags(t *testing.T) {
+	const unknownFlag uint32 = 1 << 7
+
+	newer := packWithHeader(t, Version{Major: CurrentVersion.Major, Minor: CurrentVersion.Minor + 1}, unknownFlag)
+	checkSampleContents(t, unpackData(t, newer))
+
+	current := packWithHeader(t, CurrentVersion, unknownFlag)
+	if _, err := Unpack(bytes.NewReader(current)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion for an unknown flag in %v, got %v", CurrentVersion, err)
+	}
+}